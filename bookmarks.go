@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	BookmarksFormat = "bookmarks"
+
+	bookmarksFolder = "Starred repositories"
+)
+
+// bookmarksRenderer writes the Netscape bookmark file format, which all major
+// browsers can import. Every group becomes a folder.
+type bookmarksRenderer struct{}
+
+func (bookmarksRenderer) render(w io.Writer, data T) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "<!DOCTYPE NETSCAPE-Bookmark-file-1>")
+	fmt.Fprintln(bw, "<!-- This is an automatically generated file.")
+	fmt.Fprintln(bw, "     It will be read and overwritten.")
+	fmt.Fprintln(bw, "     DO NOT EDIT! -->")
+	fmt.Fprintln(bw, `<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">`)
+	fmt.Fprintln(bw, "<TITLE>Bookmarks</TITLE>")
+	fmt.Fprintln(bw, "<H1>Bookmarks</H1>")
+	fmt.Fprintln(bw, "<DL><p>")
+
+	folder := bookmarksFolder
+	if data.User != "" {
+		folder += " of " + data.User
+	}
+	fmt.Fprintf(bw, "    <DT><H3%s>%s</H3>\n", addDate(data.FetchedAt), html.EscapeString(folder))
+	fmt.Fprintln(bw, "    <DL><p>")
+
+	for _, k := range data.Keys {
+		fmt.Fprintf(bw, "        <DT><H3%s>%s</H3>\n", addDate(data.FetchedAt), html.EscapeString(k))
+		fmt.Fprintln(bw, "        <DL><p>")
+		for _, s := range data.Stars[k] {
+			fmt.Fprintf(bw, "            <DT><A HREF=\"%s\"%s>%s</A>\n", html.EscapeString(s.Url), addDate(s.StarredAt), html.EscapeString(s.NameWithOwner))
+			if d := strings.TrimSpace(s.Description); d != "" {
+				fmt.Fprintf(bw, "            <DD>%s\n", html.EscapeString(strings.Join(strings.Fields(d), " ")))
+			}
+		}
+		fmt.Fprintln(bw, "        </DL><p>")
+	}
+
+	fmt.Fprintln(bw, "    </DL><p>")
+	fmt.Fprintln(bw, "</DL><p>")
+
+	return bw.Flush()
+}
+
+// addDate returns the ADD_DATE attribute for t, or nothing for the zero time.
+func addDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" ADD_DATE=\"%d\"", t.Unix())
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const defaultFallbackCategory = "Other"
+
+// Category is a user defined group of repositories. A repository belongs to the
+// category if any of its rules matches.
+type Category struct {
+	Name  string `yaml:"name" mapstructure:"name"`   // Name of the category
+	Rules []Rule `yaml:"rules" mapstructure:"rules"` // Rules, any of them has to match
+}
+
+// Rule matches repositories. All fields that are set have to match.
+type Rule struct {
+	Name        string `yaml:"name,omitempty" mapstructure:"name"`               // Glob on the name, or on name with owner if it contains a slash
+	Owner       string `yaml:"owner,omitempty" mapstructure:"owner"`             // Owner of the repository
+	Topic       string `yaml:"topic,omitempty" mapstructure:"topic"`             // One of the topics of the repository
+	Language    string `yaml:"language,omitempty" mapstructure:"language"`       // Primary language of the repository
+	Description string `yaml:"description,omitempty" mapstructure:"description"` // Regular expression on the description
+	MinStars    int    `yaml:"min_stars,omitempty" mapstructure:"min_stars"`     // Minimum number of stars
+}
+
+// categorizer assigns repositories to the first matching category.
+type categorizer struct {
+	categories []Category
+	patterns   [][]*regexp.Regexp // compiled description patterns per category and rule
+	fallback   string
+}
+
+// newCategorizer validates the categories and compiles their rules.
+func newCategorizer(categories []Category, fallback string) (*categorizer, error) {
+	if fallback == "" {
+		fallback = defaultFallbackCategory
+	}
+	c := &categorizer{categories: categories, fallback: fallback}
+
+	for _, cat := range categories {
+		if cat.Name == "" {
+			return nil, fmt.Errorf("category without name")
+		}
+		rx := make([]*regexp.Regexp, len(cat.Rules))
+		for i, r := range cat.Rules {
+			if r.Name != "" {
+				if _, err := path.Match(r.Name, ""); err != nil {
+					return nil, fmt.Errorf("category %q: invalid name pattern %q: %v", cat.Name, r.Name, err)
+				}
+			}
+			if r.Description != "" {
+				var err error
+				if rx[i], err = regexp.Compile("(?i)" + r.Description); err != nil {
+					return nil, fmt.Errorf("category %q: invalid description pattern %q: %v", cat.Name, r.Description, err)
+				}
+			}
+		}
+		c.patterns = append(c.patterns, rx)
+	}
+
+	return c, nil
+}
+
+// categorize returns the name of the first category with a matching rule,
+// or the fallback category.
+func (c *categorizer) categorize(s Star) string {
+	for i, cat := range c.categories {
+		for j, r := range cat.Rules {
+			if r.matches(s, c.patterns[i][j]) {
+				return cat.Name
+			}
+		}
+	}
+	return c.fallback
+}
+
+// matches reports whether all fields of the rule that are set match the star.
+// A rule without any field never matches.
+func (r Rule) matches(s Star, description *regexp.Regexp) bool {
+	if r == (Rule{}) {
+		return false
+	}
+	if r.Name != "" {
+		name := s.Name
+		if strings.Contains(r.Name, "/") {
+			name = s.NameWithOwner
+		}
+		if ok, _ := path.Match(strings.ToLower(r.Name), strings.ToLower(name)); !ok {
+			return false
+		}
+	}
+	if r.Owner != "" && !strings.EqualFold(r.Owner, s.Owner()) {
+		return false
+	}
+	if r.Topic != "" && !hasTopic(s, r.Topic) {
+		return false
+	}
+	if r.Language != "" && !strings.EqualFold(r.Language, s.Language) {
+		return false
+	}
+	if description != nil && !description.MatchString(s.Description) {
+		return false
+	}
+	return s.Stars >= r.MinStars
+}
+
+func hasTopic(s Star, topic string) bool {
+	for _, t := range s.Topics {
+		if strings.EqualFold(t, topic) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCategorize(t *testing.T) {
+	categories := []Category{
+		{Name: "Kubernetes operators", Rules: []Rule{
+			{Topic: "kubernetes-operator"},
+			{Name: "*-operator", Language: "Go"},
+		}},
+		{Name: "CLI tools", Rules: []Rule{
+			{Topic: "cli", MinStars: 100},
+			{Description: `command[- ]line`},
+		}},
+		{Name: "Fonts", Rules: []Rule{
+			{Owner: "ryanoasis"},
+			{Name: "*/*-fonts"},
+		}},
+	}
+
+	c, err := newCategorizer(categories, "")
+	if err != nil {
+		t.Fatalf("newCategorizer() returned an error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		star     Star
+		expected string
+	}{
+		{"Topic", Star{Name: "x", NameWithOwner: "a/x", Topics: []string{"Kubernetes-Operator"}}, "Kubernetes operators"},
+		{"Name glob and language", Star{Name: "etcd-operator", NameWithOwner: "a/etcd-operator", Language: "go"}, "Kubernetes operators"},
+		{"Name glob, wrong language", Star{Name: "etcd-operator", NameWithOwner: "a/etcd-operator", Language: "Rust"}, defaultFallbackCategory},
+		{"Topic below min stars", Star{Name: "tool", NameWithOwner: "a/tool", Topics: []string{"cli"}, Stars: 99}, defaultFallbackCategory},
+		{"Topic with min stars", Star{Name: "tool", NameWithOwner: "a/tool", Topics: []string{"cli"}, Stars: 100}, "CLI tools"},
+		{"Description", Star{Name: "jq", NameWithOwner: "a/jq", Description: "Command-line JSON processor"}, "CLI tools"},
+		{"Owner", Star{Name: "nerd-fonts", NameWithOwner: "ryanoasis/nerd-fonts"}, "Fonts"},
+		{"Name with owner glob", Star{Name: "ibm-fonts", NameWithOwner: "ibm/ibm-fonts"}, "Fonts"},
+		{"Priority", Star{Name: "cli-operator", NameWithOwner: "a/cli-operator", Language: "Go", Topics: []string{"cli"}, Stars: 500}, "Kubernetes operators"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.categorize(tt.star); got != tt.expected {
+				t.Errorf("categorize() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewCategorizerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []Category
+	}{
+		{"Missing name", []Category{{Rules: []Rule{{Owner: "a"}}}}},
+		{"Invalid regexp", []Category{{Name: "x", Rules: []Rule{{Description: "("}}}}},
+		{"Invalid glob", []Category{{Name: "x", Rules: []Rule{{Name: "["}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newCategorizer(tt.categories, ""); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestProcessStarsByCategory(t *testing.T) {
+	config := &Config{
+		Categories:       []Category{{Name: "Go tools", Rules: []Rule{{Language: "Go"}}}},
+		FallbackCategory: "Misc",
+	}
+	list := []Star{
+		{NameWithOwner: "a/tool", Language: "Go"},
+		{NameWithOwner: "a/site", Language: "HTML"},
+	}
+
+	stars, total, err := processStars(config, list)
+	if err != nil {
+		t.Fatalf("processStars() returned an error: %v", err)
+	}
+	if total != 2 || len(stars["Go tools"]) != 1 || len(stars["Misc"]) != 1 {
+		t.Errorf("Unexpected categories %+v", stars)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const changelogHeader = "# Changelog\n"
+
+//go:embed changelog_template.md
+var changelogTemplate string
+
+// Changelog describes what changed between two snapshots.
+type Changelog struct {
+	Since      time.Time   // Fetch time of the previous snapshot
+	Until      time.Time   // Fetch time of the current snapshot
+	Added      []Star      // Newly starred repositories
+	Removed    []Star      // Unstarred (or deleted) repositories
+	Renamed    []Rename    // Repositories with a new name
+	Archived   []Star      // Newly archived repositories
+	StarDeltas []StarDelta // Repositories whose star count changed by at least the minimum delta
+}
+
+// Rename is a repository that got a new name or owner.
+type Rename struct {
+	OldName string // Previous name with owner
+	Star    Star   // The repository with its new name
+}
+
+// StarDelta is a change of the star count of a repository.
+type StarDelta struct {
+	Star     Star // The repository with its current star count
+	Previous int  // The previous star count
+	Delta    int  // The difference to the previous star count
+}
+
+// Empty reports whether there are no changes.
+func (c *Changelog) Empty() bool {
+	return c == nil || len(c.Added)+len(c.Removed)+len(c.Renamed)+len(c.Archived)+len(c.StarDeltas) == 0
+}
+
+// diffSnapshots compares the stars of two snapshots. Repositories are matched by node ID,
+// or URL if the ID is missing. Only star count changes of at least minDelta are reported.
+// It returns nil if there is no previous snapshot.
+func diffSnapshots(prev, cur *Snapshot, minDelta int) *Changelog {
+	if prev == nil || cur == nil {
+		return nil
+	}
+
+	c := &Changelog{Since: prev.FetchedAt, Until: cur.FetchedAt}
+	if minDelta < 1 {
+		minDelta = 1
+	}
+
+	byID := make(map[string]int)
+	byUrl := make(map[string]int)
+	for i, s := range prev.Stars {
+		if s.ID != "" {
+			byID[s.ID] = i
+		}
+		byUrl[strings.ToLower(s.Url)] = i
+	}
+
+	matched := make(map[int]bool)
+	for _, s := range cur.Stars {
+		i, ok := byID[s.ID]
+		if s.ID == "" || !ok {
+			i, ok = byUrl[strings.ToLower(s.Url)]
+		}
+		if !ok || matched[i] {
+			c.Added = append(c.Added, s)
+			continue
+		}
+		matched[i] = true
+
+		old := prev.Stars[i]
+		if !strings.EqualFold(old.NameWithOwner, s.NameWithOwner) {
+			c.Renamed = append(c.Renamed, Rename{OldName: old.NameWithOwner, Star: s})
+		}
+		if s.Archived && !old.Archived {
+			c.Archived = append(c.Archived, s)
+		}
+		if d := s.Stars - old.Stars; d >= minDelta || -d >= minDelta {
+			c.StarDeltas = append(c.StarDeltas, StarDelta{Star: s, Previous: old.Stars, Delta: d})
+		}
+	}
+
+	for i, s := range prev.Stars {
+		if !matched[i] {
+			c.Removed = append(c.Removed, s)
+		}
+	}
+
+	sort.SliceStable(c.StarDeltas, func(i, j int) bool {
+		return abs(c.StarDeltas[i].Delta) > abs(c.StarDeltas[j].Delta)
+	})
+
+	return c
+}
+
+// writeChangelog prepends the changes to the changelog file, newest entries first.
+// Nothing is written if there are no changes.
+func writeChangelog(path string, changes *Changelog) error {
+	if changes.Empty() {
+		return nil
+	}
+
+	t, err := template.New("changelog").Funcs(templateFuncs).Parse(changelogTemplate)
+	if err != nil {
+		return fmt.Errorf("error parsing changelog template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(changelogHeader)
+	if err := t.Execute(&buf, changes); err != nil {
+		return fmt.Errorf("error rendering changelog: %v", err)
+	}
+
+	if exists(path) {
+		old, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+		buf.WriteString(strings.TrimLeft(strings.TrimPrefix(string(old), changelogHeader), "\n"))
+	}
+
+	_, err = writeFile(path, buf.Bytes())
+	return err
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
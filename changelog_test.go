@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	prev := &Snapshot{
+		FetchedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Stars: []Star{
+			{ID: "R1", Url: "https://github.com/user/repo1", NameWithOwner: "user/repo1", Stars: 100},
+			{ID: "R2", Url: "https://github.com/user/old-name", NameWithOwner: "user/old-name", Stars: 5},
+			{Url: "https://github.com/user/repo3", NameWithOwner: "user/repo3", Stars: 5},
+			{ID: "R4", Url: "https://github.com/user/gone", NameWithOwner: "user/gone", Stars: 5},
+		},
+	}
+	cur := &Snapshot{
+		FetchedAt: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		Stars: []Star{
+			{ID: "R1", Url: "https://github.com/user/repo1", NameWithOwner: "user/repo1", Stars: 150},
+			{ID: "R2", Url: "https://github.com/other/new-name", NameWithOwner: "other/new-name", Stars: 7},
+			{ID: "R3", Url: "https://github.com/user/repo3", NameWithOwner: "user/repo3", Stars: 5, Archived: true},
+			{ID: "R5", Url: "https://github.com/user/new", NameWithOwner: "user/new", Stars: 1},
+		},
+	}
+
+	c := diffSnapshots(prev, cur, 10)
+
+	if len(c.Added) != 1 || c.Added[0].NameWithOwner != "user/new" {
+		t.Errorf("Expected 'user/new' to be added, got %+v", c.Added)
+	}
+	if len(c.Removed) != 1 || c.Removed[0].NameWithOwner != "user/gone" {
+		t.Errorf("Expected 'user/gone' to be removed, got %+v", c.Removed)
+	}
+	if len(c.Renamed) != 1 || c.Renamed[0].OldName != "user/old-name" || c.Renamed[0].Star.NameWithOwner != "other/new-name" {
+		t.Errorf("Expected 'user/old-name' to be renamed, got %+v", c.Renamed)
+	}
+	if len(c.Archived) != 1 || c.Archived[0].NameWithOwner != "user/repo3" {
+		t.Errorf("Expected 'user/repo3' to be archived, got %+v", c.Archived)
+	}
+	if len(c.StarDeltas) != 1 || c.StarDeltas[0].Delta != 50 || c.StarDeltas[0].Previous != 100 {
+		t.Errorf("Expected a star delta of 50 for 'user/repo1', got %+v", c.StarDeltas)
+	}
+	if !c.Since.Equal(prev.FetchedAt) || !c.Until.Equal(cur.FetchedAt) {
+		t.Errorf("Unexpected changelog period %v - %v", c.Since, c.Until)
+	}
+
+	if diffSnapshots(nil, cur, 10) != nil {
+		t.Error("Expected no changelog without a previous snapshot")
+	}
+	if !diffSnapshots(cur, cur, 1).Empty() {
+		t.Error("Expected no changes between identical snapshots")
+	}
+}
+
+func TestWriteChangelog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	day1 := &Changelog{
+		Until: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Added: []Star{{NameWithOwner: "user/repo1", Url: "https://github.com/user/repo1", Description: "First"}},
+	}
+	day2 := &Changelog{
+		Until:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		Removed: []Star{{NameWithOwner: "user/repo1", Url: "https://github.com/user/repo1"}},
+	}
+
+	for _, c := range []*Changelog{day1, day2, {}} {
+		if err := writeChangelog(path, c); err != nil {
+			t.Fatalf("writeChangelog() returned an error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read changelog: %v", err)
+	}
+	out := string(data)
+
+	if strings.Count(out, changelogHeader) != 1 || !strings.HasPrefix(out, changelogHeader) {
+		t.Errorf("Expected a single changelog header, got:\n%s", out)
+	}
+	i1, i2 := strings.Index(out, "## 2024-05-01"), strings.Index(out, "## 2024-05-02")
+	if i1 < 0 || i2 < 0 || i2 > i1 {
+		t.Errorf("Expected newest entry first, got:\n%s", out)
+	}
+	if !strings.Contains(out, "  - [user/repo1](https://github.com/user/repo1) - First") {
+		t.Errorf("Expected starred repository in changelog, got:\n%s", out)
+	}
+}
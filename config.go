@@ -9,17 +9,49 @@ import (
 
 // Config represents the application configuration settings.
 type Config struct {
-	GithubUser    string   `yaml:"github_user"`      // GitHub username
-	GithubToken   string   `yaml:"github_token"`     // GitHub access token
-	OutputFile    string   `yaml:"output_file"`      // Path to the output file
-	OutputFormat  string   `yaml:"output_format"`    // Format of the output (e.g., "list" or "table")
-	IgnoreRepos   []string `yaml:"ignore_repos"`     // List of repositories to ignore
-	WithTOC       bool     `yaml:"with_toc"`         // Whether to include a table of contents
-	WithStars     bool     `yaml:"with_stars"`       // Whether to include star counts
-	WithLicense   bool     `yaml:"with_license"`     // Whether to include license information
-	WithBackToTop bool     `yaml:"with_back_to_top"` // Whether to include "back to top" links
-	Test          bool     `yaml:"test"`             // Whether to use test data
-	RateLimit     int      `yaml:"rate_limit"`       // Number of API requests per second
+	GithubUser           string     `yaml:"github_user"`            // GitHub username
+	GithubToken          string     `yaml:"github_token"`           // GitHub access token
+	GraphQLURL           string     `yaml:"graphql_url"`            // GraphQL endpoint, set for GitHub Enterprise Server
+	OutputFile           string     `yaml:"output_file"`            // Path to the output file
+	OutputFormat         string     `yaml:"output_format"`          // Format of the output (e.g., "list" or "table")
+	Outputs              []Output   `yaml:"outputs,omitempty"`      // Outputs rendered from the same fetch, instead of output_file and output_format
+	Split                bool       `yaml:"split"`                  // Whether to write a file per group and an index to the output file
+	SplitDir             string     `yaml:"split_dir"`              // Directory of the files per group, relative to the output file
+	Inject               bool       `yaml:"inject"`                 // Whether to replace only the content between the markers of the output file
+	DryRun               bool       `yaml:"dry_run"`                // Whether to print a diff of the changes instead of writing them
+	Check                bool       `yaml:"check"`                  // Whether to fail if the output files are out of date, without writing them
+	OutputDir            string     `yaml:"output_dir"`             // Directory of the site
+	TemplateDir          string     `yaml:"template_dir"`           // Directory with templates replacing the built-in site pages
+	ListTemplateDir      string     `yaml:"list_template_dir"`      // Directory with a main template and partials replacing those of the list template
+	RecentLimit          int        `yaml:"recent_limit"`           // Number of repositories on the recent page of the site
+	Columns              []string   `yaml:"columns,omitempty"`      // Columns of the csv and tsv formats
+	FeedLimit            int        `yaml:"feed_limit"`             // Maximum number of entries of the atom and rss formats
+	FeedMaxAgeDays       int        `yaml:"feed_max_age"`           // Maximum age in days of entries of the atom and rss formats
+	IgnoreRepos          []string   `yaml:"ignore_repos"`           // List of repositories to ignore
+	Filter               string     `yaml:"filter"`                 // Expression repositories have to match to be listed
+	Exclude              []string   `yaml:"exclude,omitempty"`      // Glob or /regexp/ patterns of repositories to exclude
+	DescriptionEmoji     string     `yaml:"description_emoji"`      // How to handle :shortcode: emoji in descriptions (keep, strip or convert)
+	DescriptionMaxLength int        `yaml:"description_max_length"` // Maximum length of descriptions, 0 for no limit
+	GroupBy              string     `yaml:"group_by"`               // How to group the repositories (e.g., "language" or "topic")
+	Categories           []Category `yaml:"categories,omitempty"`   // Custom categories, in priority order
+	FallbackCategory     string     `yaml:"fallback_category"`      // Category for repositories matching no category
+	Sort                 string     `yaml:"sort"`                   // Order of the repositories in a group (e.g., "stars:desc")
+	GroupSort            string     `yaml:"group_sort"`             // Order of the groups (e.g., "name", "count" or "custom")
+	GroupOrder           []string   `yaml:"group_order,omitempty"`  // Order of the groups for the custom group sort
+	WithTOC              bool       `yaml:"with_toc"`               // Whether to include a table of contents
+	WithStars            bool       `yaml:"with_stars"`             // Whether to include star counts
+	WithLicense          bool       `yaml:"with_license"`           // Whether to include license information
+	WithBackToTop        bool       `yaml:"with_back_to_top"`       // Whether to include "back to top" links
+	Source               string     `yaml:"source"`                 // Name of the star source (e.g., "github" or "file")
+	SourceFile           string     `yaml:"source_file"`            // Path to the stars file used by the file source
+	SnapshotFile         string     `yaml:"snapshot_file"`          // Path to the snapshot of the fetched stars
+	FromSnapshot         bool       `yaml:"from_snapshot"`          // Whether to render from the snapshot instead of fetching
+	Incremental          bool       `yaml:"incremental"`            // Whether to only fetch stars newer than the snapshot
+	FullRefreshDays      int        `yaml:"full_refresh_days"`      // Days after which an incremental fetch fetches all stars again
+	ChangelogFile        string     `yaml:"changelog_file"`         // Path to the changelog, empty to disable
+	ChangelogMinDelta    int        `yaml:"changelog_min_delta"`    // Minimum star count change to report
+	Test                 bool       `yaml:"test"`                   // Whether to use test data
+	RateLimit            int        `yaml:"rate_limit"`             // Number of API requests per second
 }
 
 // LoadConfig loads the configuration from a YAML file.
@@ -4,6 +4,8 @@ import (
 	"os"
 	"reflect"
 	"testing"
+
+	"github.com/spf13/viper"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -115,3 +117,19 @@ func TestSaveConfig(t *testing.T) {
 		t.Errorf("Loaded config does not match saved config. Got %+v, want %+v", loadedConfig, config)
 	}
 }
+
+func TestAliasConfigKeys(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("group_sort", "count")
+	viper.Set("fallback_category", "Misc")
+	aliasConfigKeys()
+
+	if got := viper.GetString("group-sort"); got != "count" {
+		t.Errorf("Expected group-sort from group_sort, got %q", got)
+	}
+	if got := viper.GetString("fallback-category"); got != "Misc" {
+		t.Errorf("Expected fallback-category from fallback_category, got %q", got)
+	}
+}
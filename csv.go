@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CSVFormat = "csv"
+	TSVFormat = "tsv"
+)
+
+// csvColumns maps the available column names to their value.
+var csvColumns = map[string]func(group string, s Star) string{
+	"group":           func(g string, _ Star) string { return g },
+	"id":              func(_ string, s Star) string { return s.ID },
+	"name":            func(_ string, s Star) string { return s.Name },
+	"owner":           func(_ string, s Star) string { return s.Owner() },
+	"name_with_owner": func(_ string, s Star) string { return s.NameWithOwner },
+	"url":             func(_ string, s Star) string { return s.Url },
+	"description":     func(_ string, s Star) string { return s.Description },
+	"language":        func(_ string, s Star) string { return s.Language },
+	"topics":          func(_ string, s Star) string { return strings.Join(s.Topics, " ") },
+	"license":         func(_ string, s Star) string { return s.License },
+	"license_url":     func(_ string, s Star) string { return s.LicenseUrl },
+	"stars":           func(_ string, s Star) string { return strconv.Itoa(s.Stars) },
+	"archived":        func(_ string, s Star) string { return strconv.FormatBool(s.Archived) },
+	"starred_at":      func(_ string, s Star) string { return formatTime(s.StarredAt) },
+	"pushed_at":       func(_ string, s Star) string { return formatTime(s.PushedAt) },
+}
+
+var defaultCSVColumns = []string{"group", "name", "owner", "url", "description", "license", "stars", "archived", "starred_at"}
+
+// csvRenderer writes one row per repository and group, with a header row.
+type csvRenderer struct {
+	comma   rune
+	columns []string
+}
+
+// newCSVRenderer validates the columns, an empty list selects the default columns.
+func newCSVRenderer(comma rune, columns []string) (csvRenderer, error) {
+	if len(columns) == 0 {
+		columns = defaultCSVColumns
+	}
+	cols := make([]string, len(columns))
+	for i, c := range columns {
+		cols[i] = strings.ToLower(strings.TrimSpace(c))
+		if _, ok := csvColumns[cols[i]]; !ok {
+			return csvRenderer{}, fmt.Errorf("unknown column %q, available: %s", c, strings.Join(csvColumnNames(), ", "))
+		}
+	}
+	return csvRenderer{comma: comma, columns: cols}, nil
+}
+
+// csvColumnNames returns the sorted names of all available columns.
+func csvColumnNames() []string {
+	names := make([]string, 0, len(csvColumns))
+	for n := range csvColumns {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r csvRenderer) render(w io.Writer, data T) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = r.comma
+
+	if err := cw.Write(r.columns); err != nil {
+		return err
+	}
+
+	row := make([]string, len(r.columns))
+	for _, k := range data.Keys {
+		for _, s := range data.Stars[k] {
+			for i, c := range r.columns {
+				row[i] = csvColumns[c](k, s)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatTime formats t as RFC 3339, or returns an empty string for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var (
+	// dryRun makes writeFile and removeFile record the changes in pendingChanges
+	// instead of touching any files, set by --dry-run and --check.
+	dryRun         bool
+	pendingChanges []fileChange
+)
+
+// fileChange is a change of an output file that wasn't written in dry-run mode.
+type fileChange struct {
+	path    string
+	old     []byte
+	new     []byte
+	created bool
+	removed bool
+}
+
+// diff returns the change as a unified diff.
+func (c fileChange) diff() (string, error) {
+	from, to := "a/"+c.path, "b/"+c.path
+	var a, b []string
+	if c.created {
+		from = "/dev/null"
+	} else {
+		a = difflib.SplitLines(string(c.old))
+	}
+	if c.removed {
+		to = "/dev/null"
+	} else {
+		b = difflib.SplitLines(string(c.new))
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        a,
+		B:        b,
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+}
+
+// removeFile removes a file, in dry-run mode it records the removal instead.
+func removeFile(path string) error {
+	if !dryRun {
+		return os.Remove(path)
+	}
+
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pendingChanges = append(pendingChanges, fileChange{path: path, old: old, removed: true})
+	return nil
+}
+
+// makeDir creates a directory and its parents, except in dry-run mode.
+func makeDir(dir string) error {
+	if dryRun {
+		return nil
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// reportChanges prints the diff of the pending changes with --dry-run and returns
+// an error with --check if any output file would change.
+func reportChanges(config *Config, w io.Writer) error {
+	if config.DryRun {
+		for _, c := range pendingChanges {
+			d, err := c.diff()
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, d); err != nil {
+				return err
+			}
+		}
+	}
+
+	if config.Check && len(pendingChanges) > 0 {
+		paths := make([]string, len(pendingChanges))
+		for i, c := range pendingChanges {
+			paths[i] = c.path
+		}
+		return fmt.Errorf("%d output files are out of date: %s", len(paths), strings.Join(paths, ", "))
+	}
+
+	return nil
+}
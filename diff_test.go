@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withDryRun enables dry-run mode for the duration of a test.
+func withDryRun(t *testing.T) {
+	t.Helper()
+	dryRun = true
+	pendingChanges = nil
+	t.Cleanup(func() {
+		dryRun = false
+		pendingChanges = nil
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	changed := filepath.Join(dir, "changed.md")
+	same := filepath.Join(dir, "same.md")
+	created := filepath.Join(dir, "created.md")
+	removed := filepath.Join(dir, "removed.md")
+	for path, content := range map[string]string{changed: "a\nb\nc\n", same: "same\n", removed: "gone\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	withDryRun(t)
+	for path, content := range map[string]string{changed: "a\nB\nc\n", same: "same\n", created: "new\n"} {
+		if _, err := writeFile(path, []byte(content)); err != nil {
+			t.Fatalf("writeFile(%s) returned an error: %v", path, err)
+		}
+	}
+	if err := removeFile(removed); err != nil {
+		t.Fatalf("removeFile() returned an error: %v", err)
+	}
+
+	if b := mustReadFile(t, changed); string(b) != "a\nb\nc\n" {
+		t.Errorf("Expected the file to be untouched, got %q", b)
+	}
+	if exists(created) || !exists(removed) {
+		t.Error("Expected no files to be created or removed")
+	}
+
+	var buf bytes.Buffer
+	err := reportChanges(&Config{DryRun: true, Check: true}, &buf)
+	if err == nil || !strings.Contains(err.Error(), "3 output files are out of date") {
+		t.Errorf("Expected the check to fail for 3 files, got %v", err)
+	}
+
+	out := buf.String()
+	expected := []string{
+		"--- a/" + changed + "\n+++ b/" + changed + "\n",
+		" a\n-b\n+B\n c\n",
+		"--- /dev/null\n+++ b/" + created + "\n",
+		"+new\n",
+		"--- a/" + removed + "\n+++ /dev/null\n",
+		"-gone\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected %q in diff:\n%s", e, out)
+		}
+	}
+	if strings.Contains(out, same) {
+		t.Errorf("Unexpected unchanged file in diff:\n%s", out)
+	}
+}
+
+func TestCheckUpToDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "README.md")
+	r, err := newRenderer(&Config{OutputFormat: "list"})
+	if err != nil {
+		t.Fatalf("newRenderer() returned an error: %v", err)
+	}
+	if err := writeList(path, r, testT()); err != nil {
+		t.Fatalf("writeList() returned an error: %v", err)
+	}
+
+	withDryRun(t)
+	if err := writeList(path, r, testT()); err != nil {
+		t.Fatalf("writeList() returned an error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := reportChanges(&Config{DryRun: true, Check: true}, &buf); err != nil {
+		t.Errorf("Expected an up to date list to pass the check, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected an empty diff, got:\n%s", buf.String())
+	}
+
+	data := testT()
+	data.WithStars = false
+	if err := writeList(path, r, data); err != nil {
+		t.Fatalf("writeList() returned an error: %v", err)
+	}
+	if err := reportChanges(&Config{Check: true}, &buf); err == nil {
+		t.Error("Expected a changed list to fail the check")
+	}
+}
+
+func TestCheckUncomparable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stars.db")
+
+	withDryRun(t)
+	if err := writeList(path, sqliteRenderer{}, testT()); err != nil {
+		t.Fatalf("writeList() returned an error: %v", err)
+	}
+	if exists(path) {
+		t.Error("Expected the database not to be written")
+	}
+
+	if err := reportChanges(&Config{DryRun: true, Check: true}, &bytes.Buffer{}); err != nil {
+		t.Errorf("Expected the database to be skipped by the check, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AtomFormat = "atom"
+	RSSFormat  = "rss"
+
+	defaultFeedLimit = 50
+)
+
+// feedRenderer writes the most recently starred repositories as Atom or RSS feed.
+type feedRenderer struct {
+	format  string
+	limit   int    // maximum number of entries, 0 for no limit
+	maxAge  int    // maximum age of entries in days, relative to the fetch time, 0 for no limit
+	webBase string // web address of the GitHub instance
+}
+
+// newFeedRenderer creates a renderer for the Atom or RSS format.
+func newFeedRenderer(format string, config *Config) (feedRenderer, error) {
+	base, err := webBaseURL(config.GraphQLURL)
+	if err != nil {
+		return feedRenderer{}, err
+	}
+	return feedRenderer{
+		format:  format,
+		limit:   config.FeedLimit,
+		maxAge:  config.FeedMaxAgeDays,
+		webBase: base.String(),
+	}, nil
+}
+
+func (r feedRenderer) render(w io.Writer, data T) error {
+	stars := r.entries(data)
+
+	// the feed is only updated with new stars, so rendering the same stars twice gives the same output
+	updated := data.FetchedAt
+	if len(stars) > 0 {
+		updated = stars[0].StarredAt
+	}
+
+	title := "Starred repositories"
+	link := creditUrl
+	if data.User != "" {
+		title += " of " + data.User
+		link = r.webBase + "/" + data.User + "?tab=stars"
+	}
+
+	var feed interface{}
+	if r.format == RSSFormat {
+		feed = newRSS(title, link, updated, stars)
+	} else {
+		feed = newAtom(title, link, data.User, updated, stars)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// entries returns the stars of all groups, each once, newest first,
+// limited by count and age.
+func (r feedRenderer) entries(data T) []Star {
+	seen := make(map[string]bool)
+	stars := make([]Star, 0, data.Total)
+	for _, k := range data.Keys {
+		for _, s := range data.Stars[k] {
+			if key := strings.ToLower(s.Url); !seen[key] {
+				seen[key] = true
+				stars = append(stars, s)
+			}
+		}
+	}
+
+	sort.SliceStable(stars, func(i, j int) bool {
+		return stars[i].StarredAt.After(stars[j].StarredAt)
+	})
+
+	if r.maxAge > 0 {
+		now := data.FetchedAt
+		if now.IsZero() {
+			now = time.Now()
+		}
+		cutoff := now.AddDate(0, 0, -r.maxAge)
+		for i, s := range stars {
+			if s.StarredAt.Before(cutoff) {
+				stars = stars[:i]
+				break
+			}
+		}
+	}
+
+	if r.limit > 0 && len(stars) > r.limit {
+		stars = stars[:r.limit]
+	}
+	return stars
+}
+
+// starCategories returns the language and topics of a star.
+func starCategories(s Star) []string {
+	c := make([]string, 0, len(s.Topics)+1)
+	if s.Language != "" {
+		c = append(c, s.Language)
+	}
+	return append(c, s.Topics...)
+}
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Updated   string      `xml:"updated"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+	Generator atomGen     `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomGen struct {
+	URI  string `xml:"uri,attr"`
+	Name string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func newAtom(title, link, user string, updated time.Time, stars []Star) atomFeed {
+	f := atomFeed{
+		Title:     title,
+		ID:        link,
+		Link:      atomLink{Href: link, Rel: "alternate"},
+		Updated:   updated.UTC().Format(time.RFC3339),
+		Generator: atomGen{URI: creditUrl, Name: appName},
+	}
+	// atom requires an author, either of the feed or of every entry
+	author := user
+	if author == "" {
+		author = appName
+	}
+	f.Author = &atomAuthor{Name: author}
+
+	for _, s := range stars {
+		e := atomEntry{
+			Title:   s.NameWithOwner,
+			ID:      s.Url,
+			Link:    atomLink{Href: s.Url, Rel: "alternate"},
+			Updated: s.StarredAt.UTC().Format(time.RFC3339),
+			Summary: s.Description,
+		}
+		for _, c := range starCategories(s) {
+			e.Categories = append(e.Categories, atomCategory{Term: c})
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func newRSS(title, link string, updated time.Time, stars []Star) rssFeed {
+	f := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        link,
+			Description: fmt.Sprintf("%s, generated by %s", title, appName),
+			Generator:   creditUrl,
+		},
+	}
+	if !updated.IsZero() {
+		f.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, s := range stars {
+		item := rssItem{
+			Title:       s.NameWithOwner,
+			Link:        s.Url,
+			GUID:        rssGUID{IsPermaLink: true, Value: s.Url},
+			Description: s.Description,
+			Categories:  starCategories(s),
+		}
+		if !s.StarredAt.IsZero() {
+			item.PubDate = s.StarredAt.UTC().Format(time.RFC1123Z)
+		}
+		f.Channel.Items = append(f.Channel.Items, item)
+	}
+	return f
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// starFilter decides which stars are kept, based on an expression and exclude patterns.
+type starFilter struct {
+	program *vm.Program
+	exclude []func(string) bool
+}
+
+// newStarFilter compiles the filter expression and the exclude patterns. Patterns
+// enclosed in slashes are regular expressions, all others are globs. Both are
+// matched case insensitive against the name with owner.
+func newStarFilter(expression string, exclude []string) (*starFilter, error) {
+	f := &starFilter{}
+
+	if strings.TrimSpace(expression) != "" {
+		program, err := expr.Compile(expression, expr.Env(starEnv(Star{})), expr.AsBool())
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter expression: %v", err)
+		}
+		f.program = program
+	}
+
+	for _, p := range exclude {
+		if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			rx, err := regexp.Compile("(?i)" + p[1:len(p)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid exclude pattern %q: %v", p, err)
+			}
+			f.exclude = append(f.exclude, rx.MatchString)
+			continue
+		}
+
+		glob := strings.ToLower(p)
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %v", p, err)
+		}
+		f.exclude = append(f.exclude, func(name string) bool {
+			ok, _ := path.Match(glob, strings.ToLower(name))
+			return ok
+		})
+	}
+
+	return f, nil
+}
+
+// keep reports whether the star passes the filter.
+func (f *starFilter) keep(s Star) (bool, error) {
+	for _, match := range f.exclude {
+		if match(s.NameWithOwner) {
+			return false, nil
+		}
+	}
+	if f.program == nil {
+		return true, nil
+	}
+
+	out, err := expr.Run(f.program, starEnv(s))
+	if err != nil {
+		return false, fmt.Errorf("error evaluating filter for %s: %v", s.NameWithOwner, err)
+	}
+	return out.(bool), nil
+}
+
+// starEnv exposes the fields of a star to filter expressions.
+func starEnv(s Star) map[string]interface{} {
+	topics := s.Topics
+	if topics == nil {
+		topics = []string{}
+	}
+	return map[string]interface{}{
+		"id":              s.ID,
+		"url":             s.Url,
+		"name":            s.Name,
+		"name_with_owner": s.NameWithOwner,
+		"owner":           s.Owner(),
+		"description":     s.Description,
+		"language":        s.Language,
+		"topics":          topics,
+		"license":         s.License,
+		"license_url":     s.LicenseUrl,
+		"stars":           s.Stars,
+		"archived":        s.Archived,
+		"starred_at":      s.StarredAt,
+		"pushed_at":       s.PushedAt,
+	}
+}
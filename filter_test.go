@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStarFilter(t *testing.T) {
+	list := []Star{
+		{NameWithOwner: "kubernetes/kubernetes", Language: "Go", Stars: 100000, Topics: []string{"containers"}, PushedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{NameWithOwner: "user/abandoned", Language: "Go", Stars: 500, Archived: true},
+		{NameWithOwner: "user/tiny", Language: "Rust", Stars: 3},
+		{NameWithOwner: "user/unknown", Stars: 200},
+		{NameWithOwner: "Awesome/awesome-go", Language: "Go", Stars: 1000, StarredAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		exclude    []string
+		expected   []string
+	}{
+		{"No filter", "", nil, []string{"kubernetes/kubernetes", "user/abandoned", "user/tiny", "user/unknown", "Awesome/awesome-go"}},
+		{"Expression", `stars >= 100 && !archived && language != ""`, nil, []string{"kubernetes/kubernetes", "Awesome/awesome-go"}},
+		{"Topics", `"containers" in topics`, nil, []string{"kubernetes/kubernetes"}},
+		{"Owner", `owner == "user" && stars > 100`, nil, []string{"user/abandoned", "user/unknown"}},
+		{"Starred at", `starred_at.Year() == 2020`, nil, []string{"Awesome/awesome-go"}},
+		{"Pushed at", `pushed_at.Year() == 2024`, nil, []string{"kubernetes/kubernetes"}},
+		{"Glob", "", []string{"user/*"}, []string{"kubernetes/kubernetes", "Awesome/awesome-go"}},
+		{"Regexp", "", []string{"/^awesome/awesome-/"}, []string{"kubernetes/kubernetes", "user/abandoned", "user/tiny", "user/unknown"}},
+		{"Both", `language == "Go"`, []string{"kubernetes/*"}, []string{"user/abandoned", "Awesome/awesome-go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filterStars(&Config{Filter: tt.expression, Exclude: tt.exclude}, list)
+			if err != nil {
+				t.Fatalf("filterStars() returned an error: %v", err)
+			}
+			names := make([]string, 0)
+			for _, s := range got {
+				names = append(names, s.NameWithOwner)
+			}
+			if len(names) != len(tt.expected) {
+				t.Fatalf("filterStars() = %v, want %v", names, tt.expected)
+			}
+			for i := range names {
+				if names[i] != tt.expected[i] {
+					t.Errorf("filterStars() = %v, want %v", names, tt.expected)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestStarFilterErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		exclude    []string
+	}{
+		{"Unknown field", "forks > 10", nil},
+		{"Not boolean", "stars + 1", nil},
+		{"Invalid regexp", "", []string{"/(/"}},
+		{"Invalid glob", "", []string{"["}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newStarFilter(tt.expression, tt.exclude); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// templateFuncs are the functions available in all templates, see the README
+// for their documentation.
+var templateFuncs = map[string]any{
+	"anchor":     anchor,
+	"humanize":   humanize,
+	"date":       formatDate,
+	"since":      since,
+	"truncate":   truncate,
+	"mdEscape":   mdEscape,
+	"htmlEscape": html.EscapeString,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"sortBy":     sortBy,
+	"groupCount": groupCount,
+	"emoji":      emoji,
+	"group":      group,
+	"entry":      entry,
+}
+
+// now returns the current time, replaced in tests.
+var now = time.Now
+
+// humanize abbreviates large numbers, e.g. 1234 to 1.2k and 2500000 to 2.5M.
+func humanize(n int) string {
+	if math.Abs(float64(n)) < 1e3 {
+		return fmt.Sprint(n)
+	}
+	// the smallest unit that stays below 1000 after rounding, e.g. 999950 is 1M, not 1000k
+	units := []struct {
+		size   float64
+		suffix string
+	}{{1e3, "k"}, {1e6, "M"}, {1e9, "B"}}
+	for i, u := range units {
+		if v := math.Round(float64(n)/u.size*10) / 10; math.Abs(v) < 1e3 || i == len(units)-1 {
+			return strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0") + u.suffix
+		}
+	}
+	return fmt.Sprint(n)
+}
+
+// formatDate formats t with the Go layout, e.g. {{ .StarredAt | date "2006-01-02" }}.
+// The zero time is formatted as an empty string.
+func formatDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// since describes how long ago t was, e.g. "3 days ago".
+func since(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	d := now().Sub(t)
+	if d < time.Minute {
+		return "just now"
+	}
+	for _, u := range []struct {
+		size time.Duration
+		name string
+	}{
+		{365 * 24 * time.Hour, "year"},
+		{30 * 24 * time.Hour, "month"},
+		{7 * 24 * time.Hour, "week"},
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	} {
+		if d >= u.size {
+			n := int(d / u.size)
+			if n == 1 {
+				return "1 " + u.name + " ago"
+			}
+			return fmt.Sprintf("%d %ss ago", n, u.name)
+		}
+	}
+	return "just now"
+}
+
+// truncate shortens s to at most n characters, ending with an ellipsis if it
+// was shortened, e.g. {{ .Description | truncate 80 }}.
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return strings.TrimRight(string(r[:n-1]), " ") + "…"
+}
+
+var mdReplacer = strings.NewReplacer(slices.Concat(tableCellEscapes, []string{
+	"#", `\#`, "\r\n", " ", "\n", " ", "\r", " ",
+})...)
+
+// mdEscape escapes the characters of s with a meaning in markdown and joins its
+// lines, so it can be used in list items and table cells.
+func mdEscape(s string) string {
+	return mdReplacer.Replace(s)
+}
+
+// sortBy returns the stars sorted by a sort specification like "stars:desc",
+// e.g. {{ range index .Stars $key | sortBy "starred-at" }}.
+func sortBy(spec string, stars []Star) ([]Star, error) {
+	sorted := map[string][]Star{"": append([]Star(nil), stars...)}
+	if err := sortStars(sorted, spec); err != nil {
+		return nil, err
+	}
+	return sorted[""], nil
+}
+
+// groupCount returns the number of repositories in a group, e.g. {{ groupCount .Stars $key }}.
+func groupCount(stars map[string][]Star, key string) int {
+	return len(stars[key])
+}
+
+// languageEmoji are the emoji of the more common languages.
+var languageEmoji = map[string]string{
+	"c":                "🔧",
+	"c#":               "#️⃣",
+	"c++":              "⚙️",
+	"css":              "🎨",
+	"dart":             "🎯",
+	"dockerfile":       "🐳",
+	"elixir":           "💧",
+	"go":               "🐹",
+	"haskell":          "λ",
+	"html":             "🌐",
+	"java":             "☕",
+	"javascript":       "🟨",
+	"jupyter notebook": "📓",
+	"kotlin":           "🟣",
+	"lua":              "🌙",
+	"markdown":         "📝",
+	"nix":              "❄️",
+	"php":              "🐘",
+	"python":           "🐍",
+	"r":                "📊",
+	"ruby":             "💎",
+	"rust":             "🦀",
+	"scala":            "🔺",
+	"shell":            "🐚",
+	"swift":            "🐦",
+	"typescript":       "🔷",
+	"vim script":       "📝",
+	"zig":              "⚡",
+}
+
+// emoji returns an emoji for a language, a package for unknown languages.
+func emoji(language string) string {
+	if e, ok := languageEmoji[strings.ToLower(strings.TrimSpace(language))]; ok {
+		return e
+	}
+	return "📦"
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+// fixedNow sets the time used by since for the duration of a test.
+func fixedNow(t *testing.T, at time.Time) {
+	t.Helper()
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = time.Now })
+}
+
+// golden compares out with the golden file of the given name, or updates the
+// file with -update.
+func golden(t *testing.T, name string, out []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file, run the test with -update to create it: %v", err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output differs from %s, run the test with -update if this is expected.\nGot:\n%s\nWant:\n%s", path, out, expected)
+	}
+}
+
+func testFuncsT() T {
+	data := testT()
+	data.Stars["Go"] = append(data.Stars["Go"], Star{
+		Url:           "https://github.com/Other/Repo3",
+		NameWithOwner: "Other/Repo3",
+		Description:   "A *fast* [web] framework",
+		Language:      "Go",
+		License:       "Apache-2.0 & MIT",
+		Stars:         2_500_000,
+		StarredAt:     time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC),
+	})
+	data.Stars["Rust"] = append(data.Stars["Rust"], Star{
+		Url:           "https://github.com/Other/Repo4",
+		NameWithOwner: "Other/Repo4",
+		Description:   "Almost a million",
+		Language:      "Rust",
+		Stars:         999_950,
+		StarredAt:     time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	})
+	return data
+}
+
+func TestTemplateFuncsGolden(t *testing.T) {
+	fixedNow(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC))
+
+	tmpl, err := template.New("funcs.tmpl").Funcs(templateFuncs).ParseFiles(filepath.Join("testdata", "funcs.tmpl"))
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, testFuncsT()); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	golden(t, "funcs", buf.Bytes())
+}
+
+func TestBuiltinTemplatesGolden(t *testing.T) {
+	for _, format := range []string{string(ListTemplate), string(TableTemplate), HTMLFormat} {
+		t.Run(format, func(t *testing.T) {
+			r, err := newRenderer(&Config{OutputFormat: format})
+			if err != nil {
+				t.Fatalf("newRenderer() returned an error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := r.render(&buf, testFuncsT()); err != nil {
+				t.Fatalf("render() returned an error: %v", err)
+			}
+			golden(t, format, buf.Bytes())
+		})
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	tests := map[int]string{
+		0:             "0",
+		999:           "999",
+		1000:          "1k",
+		1234:          "1.2k",
+		12_345:        "12.3k",
+		2_500_000:     "2.5M",
+		999_950:       "1M",
+		999_949:       "999.9k",
+		-999_950:      "-1M",
+		1_249_950:     "1.2M",
+		1_000_000_000: "1B",
+		-1500:         "-1.5k",
+	}
+	for n, expected := range tests {
+		if got := humanize(n); got != expected {
+			t.Errorf("humanize(%d) = %q, want %q", n, got, expected)
+		}
+	}
+}
+
+func TestSince(t *testing.T) {
+	at := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	fixedNow(t, at)
+
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{time.Time{}, ""},
+		{at.Add(-30 * time.Second), "just now"},
+		{at.Add(-time.Minute), "1 minute ago"},
+		{at.Add(-5 * time.Hour), "5 hours ago"},
+		{at.AddDate(0, 0, -3), "3 days ago"},
+		{at.AddDate(0, 0, -14), "2 weeks ago"},
+		{at.AddDate(0, -2, 0), "2 months ago"},
+		{at.AddDate(-1, 0, 0), "1 year ago"},
+	}
+	for _, tt := range tests {
+		if got := since(tt.t); got != tt.expected {
+			t.Errorf("since(%v) = %q, want %q", tt.t, got, tt.expected)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		n        int
+		s        string
+		expected string
+	}{
+		{10, "short", "short"},
+		{10, "exactly 10", "exactly 10"},
+		{10, "a bit too long", "a bit too…"},
+		{6, "héllo wörld", "héllo…"},
+		{0, "unlimited", "unlimited"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.n, tt.s); got != tt.expected {
+			t.Errorf("truncate(%d, %q) = %q, want %q", tt.n, tt.s, got, tt.expected)
+		}
+	}
+}
+
+func TestMdEscape(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"a *b* _c_ [d](e) <f> | `g` #h", "a \\*b\\* \\_c\\_ \\[d\\](e) \\<f\\> \\| \\`g\\` \\#h"},
+		{`C:\path`, `C:\\path`},
+		{"line one\nline two", "line one line two"},
+		{"windows\r\nline\rmac", "windows line mac"},
+		{"# heading\n\n- item\n| cell |", "\\# heading  - item \\| cell \\|"},
+	}
+	for _, tt := range tests {
+		if got := mdEscape(tt.in); got != tt.expected {
+			t.Errorf("mdEscape(%q) = %q, want %q", tt.in, got, tt.expected)
+		}
+	}
+}
+
+func TestSortByInvalid(t *testing.T) {
+	if _, err := sortBy("forks", nil); err == nil {
+		t.Error("Expected an error for an unknown sort order")
+	}
+}
+
+func TestEmoji(t *testing.T) {
+	for language, expected := range map[string]string{"Go": "🐹", "rust": "🦀", "Jupyter Notebook": "📓", "Brainfuck": "📦", "": "📦"} {
+		if got := emoji(language); got != expected {
+			t.Errorf("emoji(%q) = %q, want %q", language, got, expected)
+		}
+	}
+}
@@ -3,6 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -13,6 +17,17 @@ import (
 	"golang.org/x/time/rate"
 )
 
+const (
+	// defaultGraphQLURL is the GraphQL endpoint of github.com.
+	defaultGraphQLURL = "https://api.github.com/graphql"
+	// defaultWebURL is the web address of github.com.
+	defaultWebURL = "https://github.com"
+
+	rateLimitInfoFile = "rate_limit_info.json"
+	// rateLimitBackoff is used when the server does not tell us when the rate limit resets.
+	rateLimitBackoff = time.Minute
+)
+
 type RateLimitInfo struct {
 	Limit     int       `json:"limit"`
 	Remaining int       `json:"remaining"`
@@ -21,15 +36,25 @@ type RateLimitInfo struct {
 
 // Star represents a starred GitHub repository with its details.
 type Star struct {
-	Url           string    // Repository URL
-	Name          string    // Repository name
-	NameWithOwner string    // Repository name with owner (e.g., "owner/repo")
-	Description   string    // Repository description
-	License       string    // Repository license
-	LicenseUrl    string    // URL to the license
-	Stars         int       // Number of stars
-	Archived      bool      // Whether the repository is archived
-	StarredAt     time.Time // When the repository was starred by the user
+	ID            string    `json:"id,omitempty" yaml:"id,omitempty"`         // Node ID of the repository
+	Url           string    `json:"url" yaml:"url"`                           // Repository URL
+	Name          string    `json:"name" yaml:"name"`                         // Repository name
+	NameWithOwner string    `json:"name_with_owner" yaml:"name_with_owner"`   // Repository name with owner (e.g., "owner/repo")
+	Description   string    `json:"description" yaml:"description"`           // Repository description
+	Language      string    `json:"language" yaml:"language"`                 // Primary language of the repository
+	Topics        []string  `json:"topics,omitempty" yaml:"topics,omitempty"` // Topics of the repository
+	License       string    `json:"license" yaml:"license"`                   // Repository license
+	LicenseUrl    string    `json:"license_url" yaml:"license_url"`           // URL to the license
+	Stars         int       `json:"stars" yaml:"stars"`                       // Number of stars
+	Archived      bool      `json:"archived" yaml:"archived"`                 // Whether the repository is archived
+	StarredAt     time.Time `json:"starred_at" yaml:"starred_at"`             // When the repository was starred by the user
+	PushedAt      time.Time `json:"pushed_at" yaml:"pushed_at"`               // When the repository was last pushed to
+}
+
+// Owner returns the owner part of NameWithOwner.
+func (s Star) Owner() string {
+	owner, _, _ := strings.Cut(s.NameWithOwner, "/")
+	return owner
 }
 
 var query struct {
@@ -45,6 +70,7 @@ var query struct {
 			Edges       []struct {
 				StarredAt time.Time
 				Node      struct {
+					ID          string
 					Description string
 					Languages   struct {
 						Edges []struct {
@@ -53,12 +79,20 @@ var query struct {
 							}
 						}
 					} `graphql:"languages(first: $lc, orderBy: {field: SIZE, direction: DESC})"`
+					RepositoryTopics struct {
+						Nodes []struct {
+							Topic struct {
+								Name string
+							}
+						}
+					} `graphql:"repositoryTopics(first: 20)"`
 					LicenseInfo struct {
 						Name     string
 						Nickname string
 						Url      string
 					}
 					IsArchived     bool
+					PushedAt       time.Time
 					IsPrivate      bool
 					Name           string
 					NameWithOwner  string
@@ -74,32 +108,44 @@ var query struct {
 	} `graphql:"user(login: $login)"`
 }
 
-// FetchStarsFunc is the function type for fetching stars
-type FetchStarsFunc func(user string, token string, rateLimit int) (map[string][]Star, int, error)
+// githubSource fetches the stars from the GitHub GraphQL API.
+type githubSource struct{}
+
+// FetchStars implements StarSource. Private repositories are skipped. As the stars are
+// fetched newest first, paging stops at the first star older than opts.Since.
+func (githubSource) FetchStars(ctx context.Context, opts FetchOptions) ([]Star, error) {
+	if opts.Token == "" {
+		return nil, errors.New("GitHub token is required. Please provide a valid token")
+	}
+	if opts.RateLimit <= 0 {
+		return nil, fmt.Errorf("invalid rate limit %d, must be greater than 0", opts.RateLimit)
+	}
 
-// DefaultFetchStars is the default implementation of FetchStarsFunc
-var DefaultFetchStars FetchStarsFunc = func(user string, token string, rateLimit int) (map[string][]Star, int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*3)
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*3)
 	defer cancel()
 
-	src := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	src := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opts.Token})
 	httpClient := oauth2.NewClient(ctx, src)
 
-	client := githubv4.NewClient(httpClient)
+	client := newGithubClient(opts.Endpoint, httpClient)
+	webURL, err := webBaseURL(opts.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	infoFile := rateLimitFile(opts.Endpoint)
 
 	vars := map[string]interface{}{
-		"login":  githubv4.String(user),
+		"login":  githubv4.String(opts.User),
 		"lc":     githubv4.Int(1),
 		"count":  githubv4.Int(50),
 		"cursor": githubv4.String(""),
 	}
 
-	stars := make(map[string][]Star)
-	total := 0
+	stars := make([]Star, 0)
 
-	rateLimiter := rate.NewLimiter(rate.Every(time.Second/time.Duration(rateLimit)), 1)
+	rateLimiter := rate.NewLimiter(rate.Every(time.Second/time.Duration(opts.RateLimit)), 1)
 
-	rateLimitInfo, err := loadRateLimitInfo()
+	rateLimitInfo, err := loadRateLimitInfo(infoFile)
 	if err != nil {
 		logger.WithError(err).Warn("Failed to load rate limit info, using default")
 	} else {
@@ -112,72 +158,137 @@ var DefaultFetchStars FetchStarsFunc = func(user string, token string, rateLimit
 	for {
 		if err := rateLimiter.Wait(ctx); err != nil {
 			logger.WithError(err).Error("Rate limit exceeded")
-			return stars, total, err
+			return stars, err
 		}
 
 		err = client.Query(ctx, &query, vars)
 		if err != nil {
 			if isRateLimitError(err) {
-				logger.WithError(err).Warn("Rate limit reached, waiting before retry")
-				time.Sleep(time.Until(query.RateLimit.ResetAt))
+				wait := rateLimitWait(query.RateLimit.ResetAt)
+				logger.WithError(err).WithField("wait", wait).Warn("Rate limit reached, waiting before retry")
+				time.Sleep(wait)
 				continue
 			}
 			logger.WithError(err).Error("Failed to query GitHub API")
-			return stars, total, err
+			return stars, err
 		}
 
-		rateLimitInfo = RateLimitInfo{
-			Limit:     query.RateLimit.Limit,
-			Remaining: query.RateLimit.Remaining,
-			ResetAt:   query.RateLimit.ResetAt,
-		}
-		if err := saveRateLimitInfo(rateLimitInfo); err != nil {
-			logger.WithError(err).Warn("Failed to save rate limit info")
-		}
+		// GitHub Enterprise Server reports no rate limit when it is disabled.
+		if query.RateLimit.Limit > 0 {
+			rateLimitInfo = RateLimitInfo{
+				Limit:     query.RateLimit.Limit,
+				Remaining: query.RateLimit.Remaining,
+				ResetAt:   query.RateLimit.ResetAt,
+			}
+			if err := saveRateLimitInfo(infoFile, rateLimitInfo); err != nil {
+				logger.WithError(err).Warn("Failed to save rate limit info")
+			}
 
-		logger.WithFields(logrus.Fields{
-			"remaining": rateLimitInfo.Remaining,
-			"reset_at":  rateLimitInfo.ResetAt,
-		}).Debug("GitHub API rate limit status")
+			logger.WithFields(logrus.Fields{
+				"remaining": rateLimitInfo.Remaining,
+				"reset_at":  rateLimitInfo.ResetAt,
+			}).Debug("GitHub API rate limit status")
+		}
 
+		reachedSince := false
 		for _, e := range query.User.StarredRepositories.Edges {
-			if e.Node.IsPrivate || isIgnored(e.Node.NameWithOwner) {
-				continue
+			if !opts.Since.IsZero() && e.StarredAt.Before(opts.Since) {
+				reachedSince = true
+				break
 			}
-
-			total++
-			lng := determineLanguage(e.Node.Languages.Edges)
-			if _, ok := stars[lng]; !ok {
-				stars[lng] = make([]Star, 0)
+			if e.Node.IsPrivate {
+				continue
 			}
 
-			lic := determineLicense(e.Node.LicenseInfo)
-
-			stars[lng] = append(stars[lng], Star{
-				Url:           e.Node.Url,
+			stars = append(stars, Star{
+				ID:            e.Node.ID,
+				Url:           repoURL(webURL, e.Node.Url, e.Node.NameWithOwner),
 				Name:          e.Node.Name,
 				NameWithOwner: e.Node.NameWithOwner,
 				Description:   e.Node.Description,
-				License:       lic,
+				Language:      determineLanguage(e.Node.Languages.Edges),
+				Topics:        topicNames(e.Node.RepositoryTopics.Nodes),
+				License:       determineLicense(e.Node.LicenseInfo),
 				LicenseUrl:    e.Node.LicenseInfo.Url,
 				Stars:         e.Node.StargazerCount,
 				Archived:      e.Node.IsArchived,
 				StarredAt:     e.StarredAt,
+				PushedAt:      e.Node.PushedAt,
 			})
 		}
 
-		if !query.User.StarredRepositories.PageInfo.HasNextPage {
+		if reachedSince || !query.User.StarredRepositories.PageInfo.HasNextPage {
 			break
 		}
 		vars["cursor"] = githubv4.String(query.User.StarredRepositories.PageInfo.EndCursor)
 	}
 
-	logger.WithField("total_stars", total).Info("Successfully fetched starred repositories")
-	return stars, total, nil
+	logger.WithField("total_stars", len(stars)).Info("Successfully fetched starred repositories")
+	return stars, nil
 }
 
-func loadRateLimitInfo() (RateLimitInfo, error) {
-	data, err := os.ReadFile("rate_limit_info.json")
+// newGithubClient creates a GraphQL client for github.com or, if endpoint
+// points somewhere else, for a GitHub Enterprise Server instance.
+func newGithubClient(endpoint string, httpClient *http.Client) *githubv4.Client {
+	if endpoint == "" || endpoint == defaultGraphQLURL {
+		return githubv4.NewClient(httpClient)
+	}
+	return githubv4.NewEnterpriseClient(endpoint, httpClient)
+}
+
+// webBaseURL derives the web address of a GitHub instance from its GraphQL endpoint,
+// e.g. https://github.example.com/api/graphql becomes https://github.example.com.
+func webBaseURL(endpoint string) (*url.URL, error) {
+	if endpoint == "" || endpoint == defaultGraphQLURL {
+		return url.Parse(defaultWebURL)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GraphQL endpoint %q: %v", endpoint, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid GraphQL endpoint %q: absolute URL required", endpoint)
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+// repoURL returns an absolute repository URL. The API usually returns one already,
+// relative or missing URLs are resolved against the web address of the instance.
+func repoURL(base *url.URL, raw, nameWithOwner string) string {
+	if raw == "" {
+		return base.JoinPath(nameWithOwner).String()
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.IsAbs() {
+		return raw
+	}
+	return base.ResolveReference(u).String()
+}
+
+// rateLimitFile returns the file the rate limit info of an endpoint is stored in,
+// so github.com and enterprise instances don't overwrite each other's state.
+func rateLimitFile(endpoint string) string {
+	if endpoint == "" || endpoint == defaultGraphQLURL {
+		return rateLimitInfoFile
+	}
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host = strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	return strings.TrimSuffix(rateLimitInfoFile, ".json") + "_" + host + ".json"
+}
+
+// rateLimitWait returns how long to wait for the rate limit to reset.
+func rateLimitWait(resetAt time.Time) time.Duration {
+	if wait := time.Until(resetAt); wait > 0 {
+		return wait
+	}
+	return rateLimitBackoff
+}
+
+func loadRateLimitInfo(file string) (RateLimitInfo, error) {
+	data, err := os.ReadFile(file)
 	if err != nil {
 		return RateLimitInfo{}, err
 	}
@@ -190,13 +301,13 @@ func loadRateLimitInfo() (RateLimitInfo, error) {
 	return info, nil
 }
 
-func saveRateLimitInfo(info RateLimitInfo) error {
+func saveRateLimitInfo(file string, info RateLimitInfo) error {
 	data, err := json.Marshal(info)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile("rate_limit_info.json", data, 0644)
+	return os.WriteFile(file, data, 0644)
 }
 
 func isRateLimitError(err error) bool {
@@ -213,6 +324,17 @@ func determineLanguage(languages []struct{ Node struct{ Name string } }) string
 	return "Unknown"
 }
 
+func topicNames(nodes []struct{ Topic struct{ Name string } }) []string {
+	if len(nodes) == 0 {
+		return nil
+	}
+	topics := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		topics = append(topics, n.Topic.Name)
+	}
+	return topics
+}
+
 func determineLicense(licenseInfo struct {
 	Name     string
 	Nickname string
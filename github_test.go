@@ -1,6 +1,14 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -29,10 +37,23 @@ func TestIsIgnored(t *testing.T) {
 }
 
 func TestTestStars(t *testing.T) {
-	stars, total := testStars()
+	ignored = nil
+	config := &Config{Test: true}
+	snap, _, err := loadStars(config)
+	if err != nil {
+		t.Fatalf("loadStars() returned an error: %v", err)
+	}
+	list, err := filterStars(config, snap.Stars)
+	if err != nil {
+		t.Fatalf("filterStars() returned an error: %v", err)
+	}
+	stars, total, err := processStars(config, list)
+	if err != nil {
+		t.Fatalf("processStars() returned an error: %v", err)
+	}
 
-	if total != 4 {
-		t.Errorf("Expected total of 4, got %d", total)
+	if total != 5 {
+		t.Errorf("Expected total of 5, got %d", total)
 	}
 
 	expectedLangs := []string{"go", "markdown", "C#", "C++"}
@@ -51,43 +72,62 @@ func TestTestStars(t *testing.T) {
 	}
 }
 
-// Mock for DefaultFetchStars function
-func mockFetchStars(user, token string, rateLimit int) (map[string][]Star, int, error) {
-	stars := make(map[string][]Star)
-	stars["go"] = []Star{
+// mockSource is a StarSource returning fixed stars
+type mockSource struct{}
+
+func (mockSource) FetchStars(_ context.Context, opts FetchOptions) ([]Star, error) {
+	if opts.User != "testuser" {
+		return nil, fmt.Errorf("unexpected user %q", opts.User)
+	}
+	return []Star{
 		{
 			Url:           "https://github.com/user/repo1",
 			Name:          "repo1",
 			NameWithOwner: "user/repo1",
 			Description:   "Test repo 1",
+			Language:      "go",
 			License:       "MIT",
 			Stars:         10,
 			Archived:      false,
 			StarredAt:     time.Now(),
 		},
-	}
-	return stars, 1, nil
+		{
+			Url:           "https://github.com/user/ignored",
+			Name:          "ignored",
+			NameWithOwner: "user/ignored",
+			Stars:         1,
+			StarredAt:     time.Now(),
+		},
+	}, nil
 }
 
 func TestFetchAndProcessStars(t *testing.T) {
-	// Save the original DefaultFetchStars function and restore it after the test
-	originalFetchStars := DefaultFetchStars
-	defer func() { DefaultFetchStars = originalFetchStars }()
+	RegisterStarSource("mock", mockSource{})
+	defer delete(starSources, "mock")
 
-	// Replace DefaultFetchStars with our mock function
-	DefaultFetchStars = mockFetchStars
+	ignored = []string{"user/ignored"}
+	defer func() { ignored = nil }()
 
 	config := &Config{
 		GithubUser:  "testuser",
 		GithubToken: "testtoken",
+		Source:      "mock",
 		Test:        false,
 		RateLimit:   5,
 	}
 
-	stars, total, err := fetchAndProcessStars(config)
+	snap, _, err := loadStars(config)
+	if err != nil {
+		t.Fatalf("loadStars() returned an error: %v", err)
+	}
 
+	list, err := filterStars(config, snap.Stars)
+	if err != nil {
+		t.Fatalf("filterStars() returned an error: %v", err)
+	}
+	stars, total, err := processStars(config, list)
 	if err != nil {
-		t.Fatalf("fetchAndProcessStars() returned an error: %v", err)
+		t.Fatalf("processStars() returned an error: %v", err)
 	}
 
 	if total != 1 {
@@ -97,4 +137,187 @@ func TestFetchAndProcessStars(t *testing.T) {
 	if len(stars["go"]) != 1 || stars["go"][0].Name != "repo1" {
 		t.Errorf("Expected 'repo1' in 'go' category")
 	}
+
+	if _, ok := stars["Unknown"]; ok {
+		t.Errorf("Expected ignored repository to be skipped")
+	}
+}
+
+func TestLoadStarsUnknownSource(t *testing.T) {
+	if _, _, err := loadStars(&Config{Source: "nope"}); err == nil {
+		t.Error("Expected an error for an unknown source")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stars.json")
+	data := `[{"url":"https://github.com/user/repo1","name":"repo1","name_with_owner":"user/repo1","language":"Go","stars":3,"starred_at":"2024-05-01T10:00:00Z"}]`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	stars, err := fileSource{}.FetchStars(context.Background(), FetchOptions{Path: path})
+	if err != nil {
+		t.Fatalf("FetchStars() returned an error: %v", err)
+	}
+	if len(stars) != 1 || stars[0].NameWithOwner != "user/repo1" || stars[0].Stars != 3 || stars[0].Language != "Go" {
+		t.Errorf("Unexpected stars %+v", stars)
+	}
+
+	if _, err := (fileSource{}).FetchStars(context.Background(), FetchOptions{}); err == nil {
+		t.Error("Expected an error without a file")
+	}
+}
+
+// chdir changes into dir for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// graphqlPages returns a handler that serves the given starredRepositories pages,
+// selected by the cursor variable of the request.
+func graphqlPages(t *testing.T, rateLimit string, pages map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer testtoken" {
+			t.Errorf("Expected bearer token, got %q", got)
+		}
+		var req struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		cursor, _ := req.Variables["cursor"].(string)
+		page, ok := pages[cursor]
+		if !ok {
+			t.Errorf("Unexpected cursor %q", cursor)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":{"rateLimit":%s,"user":{"starredRepositories":%s}}}`, rateLimit, page)
+	}
+}
+
+func TestDefaultFetchStarsEnterprise(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	pages := map[string]string{
+		"": `{"isOverLimit":false,"totalCount":3,"edges":[
+			{"starredAt":"2024-05-01T10:00:00Z","node":{"description":"Operator","languages":{"edges":[{"node":{"name":"Go"}}]},
+			 "repositoryTopics":{"nodes":[{"topic":{"name":"kubernetes"}},{"topic":{"name":"operator"}}]},
+			 "licenseInfo":{"name":"MIT License","nickname":"","url":"http://choosealicense.com/licenses/mit/"},
+			 "isArchived":false,"isPrivate":false,"name":"operator","nameWithOwner":"platform/operator","stargazerCount":12,
+			 "url":"https://ghe.example.com/platform/operator"}},
+			{"starredAt":"2024-04-01T10:00:00Z","node":{"description":"Secret","languages":{"edges":[]},
+			 "licenseInfo":{"name":"","nickname":"","url":""},
+			 "isArchived":false,"isPrivate":true,"name":"secret","nameWithOwner":"platform/secret","stargazerCount":1,"url":""}}],
+			"pageInfo":{"endCursor":"c1","hasNextPage":true}}`,
+		"c1": `{"isOverLimit":false,"totalCount":3,"edges":[
+			{"starredAt":"2024-03-01T10:00:00Z","node":{"description":"Scripts","languages":{"edges":[{"node":{"name":"Shell"}}]},
+			 "licenseInfo":{"name":"","nickname":"","url":""},
+			 "isArchived":true,"isPrivate":false,"name":"scripts","nameWithOwner":"ops/scripts","stargazerCount":3,"url":"/ops/scripts"}}],
+			"pageInfo":{"endCursor":"c2","hasNextPage":false}}`,
+	}
+
+	srv := httptest.NewServer(http.StripPrefix("/api/graphql", graphqlPages(t, "null", pages)))
+	defer srv.Close()
+
+	stars, err := githubSource{}.FetchStars(context.Background(), FetchOptions{
+		User:      "octocat",
+		Token:     "testtoken",
+		Endpoint:  srv.URL + "/api/graphql",
+		RateLimit: 100,
+	})
+	if err != nil {
+		t.Fatalf("FetchStars() returned an error: %v", err)
+	}
+
+	if len(stars) != 2 {
+		t.Fatalf("Expected 2 stars, got %d", len(stars))
+	}
+	if stars[0].Language != "Go" || stars[0].Url != "https://ghe.example.com/platform/operator" {
+		t.Errorf("Expected 'platform/operator' with its enterprise URL, got %+v", stars[0])
+	}
+	if !reflect.DeepEqual(stars[0].Topics, []string{"kubernetes", "operator"}) || stars[1].Topics != nil {
+		t.Errorf("Unexpected topics %v and %v", stars[0].Topics, stars[1].Topics)
+	}
+	if stars[1].Language != "Shell" || stars[1].Url != srv.URL+"/ops/scripts" {
+		t.Errorf("Expected relative URL to be resolved against %s, got %+v", srv.URL, stars[1])
+	}
+	if _, err := os.Stat(rateLimitFile(srv.URL + "/api/graphql")); !os.IsNotExist(err) {
+		t.Errorf("Expected no rate limit info to be saved when rate limiting is disabled")
+	}
+}
+
+func TestDefaultFetchStarsSavesRateLimitPerHost(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	pages := map[string]string{
+		"": `{"isOverLimit":false,"totalCount":0,"edges":[],"pageInfo":{"endCursor":"","hasNextPage":false}}`,
+	}
+	srv := httptest.NewServer(graphqlPages(t, `{"limit":5000,"remaining":4999,"resetAt":"2024-05-01T11:00:00Z"}`, pages))
+	defer srv.Close()
+
+	endpoint := srv.URL + "/api/graphql"
+	opts := FetchOptions{User: "octocat", Token: "testtoken", Endpoint: endpoint, RateLimit: 100}
+	if _, err := (githubSource{}).FetchStars(context.Background(), opts); err != nil {
+		t.Fatalf("FetchStars() returned an error: %v", err)
+	}
+
+	if exists(rateLimitInfoFile) {
+		t.Errorf("Expected enterprise rate limit info not to be stored in %s", rateLimitInfoFile)
+	}
+	info, err := loadRateLimitInfo(rateLimitFile(endpoint))
+	if err != nil {
+		t.Fatalf("Failed to load rate limit info: %v", err)
+	}
+	if info.Limit != 5000 || info.Remaining != 4999 {
+		t.Errorf("Unexpected rate limit info %+v", info)
+	}
+}
+
+func TestWebBaseURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		expected string
+		wantErr  bool
+	}{
+		{"", "https://github.com", false},
+		{defaultGraphQLURL, "https://github.com", false},
+		{"https://ghe.example.com/api/graphql", "https://ghe.example.com", false},
+		{"http://localhost:8080/api/graphql", "http://localhost:8080", false},
+		{"ghe.example.com/api/graphql", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			u, err := webBaseURL(tt.endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("webBaseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && u.String() != tt.expected {
+				t.Errorf("webBaseURL() = %s, want %s", u, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRateLimitFile(t *testing.T) {
+	if got := rateLimitFile(""); got != rateLimitInfoFile {
+		t.Errorf("rateLimitFile(\"\") = %s, want %s", got, rateLimitInfoFile)
+	}
+	if got := rateLimitFile("https://ghe.example.com:8443/api/graphql"); got != "rate_limit_info_ghe.example.com_8443.json" {
+		t.Errorf("Unexpected rate limit file for enterprise endpoint: %s", got)
+	}
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	GroupByLanguage    = "language"
+	GroupByTopic       = "topic"
+	GroupByOwner       = "owner"
+	GroupByLicense     = "license"
+	GroupByStarredYear = "starred-year"
+	GroupByNone        = "none"
+	GroupByCategory    = "category"
+
+	unknownGroup   = "Unknown"
+	noTopicGroup   = "No topic"
+	noLicenseGroup = "No license"
+	allGroup       = "All"
+)
+
+var availableGroupings = []string{GroupByLanguage, GroupByTopic, GroupByOwner, GroupByLicense, GroupByStarredYear, GroupByCategory, GroupByNone}
+
+// grouping returns how the stars are grouped. Without explicit setting, they are
+// grouped by category if categories are configured and by language otherwise.
+func grouping(config *Config) string {
+	if config.GroupBy != "" {
+		return strings.ToLower(config.GroupBy)
+	}
+	if len(config.Categories) > 0 {
+		return GroupByCategory
+	}
+	return GroupByLanguage
+}
+
+// newGrouper returns the function that determines the groups of a star.
+func newGrouper(config *Config) (func(Star) []string, error) {
+	by := grouping(config)
+	if by == GroupByCategory {
+		c, err := newCategorizer(config.Categories, config.FallbackCategory)
+		if err != nil {
+			return nil, err
+		}
+		return func(s Star) []string { return []string{c.categorize(s)} }, nil
+	}
+
+	if _, err := groupKeys(Star{}, by); err != nil {
+		return nil, err
+	}
+	return func(s Star) []string {
+		keys, _ := groupKeys(s, by)
+		return keys
+	}, nil
+}
+
+// groupKeys returns the groups a star belongs to. Only grouping by topic
+// may put a star into more than one group.
+func groupKeys(s Star, by string) ([]string, error) {
+	switch by {
+	case GroupByLanguage:
+		if s.Language == "" {
+			return []string{unknownGroup}, nil
+		}
+		return []string{s.Language}, nil
+	case GroupByTopic:
+		if len(s.Topics) == 0 {
+			return []string{noTopicGroup}, nil
+		}
+		return s.Topics, nil
+	case GroupByOwner:
+		return []string{s.Owner()}, nil
+	case GroupByLicense:
+		if s.License == "" {
+			return []string{noLicenseGroup}, nil
+		}
+		return []string{s.License}, nil
+	case GroupByStarredYear:
+		if s.StarredAt.IsZero() {
+			return []string{unknownGroup}, nil
+		}
+		return []string{strconv.Itoa(s.StarredAt.Year())}, nil
+	case GroupByNone:
+		return []string{allGroup}, nil
+	default:
+		return nil, fmt.Errorf("unknown grouping %q, available: %s", by, strings.Join(availableGroupings, ", "))
+	}
+}
+
+// groupStars puts the stars into the groups returned by group.
+func groupStars(list []Star, group func(Star) []string) map[string][]Star {
+	stars := make(map[string][]Star)
+	for _, s := range list {
+		for _, k := range group(s) {
+			stars[k] = append(stars[k], s)
+		}
+	}
+	return stars
+}
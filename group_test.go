@@ -0,0 +1,95 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGroupStars(t *testing.T) {
+	list := []Star{
+		{
+			NameWithOwner: "kubernetes/kubectl",
+			Language:      "Go",
+			Topics:        []string{"kubernetes", "cli"},
+			License:       "Apache-2.0",
+			StarredAt:     time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			NameWithOwner: "user/dotfiles",
+			Language:      "Shell",
+			Topics:        []string{"cli"},
+			StarredAt:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			NameWithOwner: "user/notes",
+		},
+	}
+
+	tests := []struct {
+		by       string
+		expected map[string][]string
+	}{
+		{GroupByLanguage, map[string][]string{
+			"Go": {"kubernetes/kubectl"}, "Shell": {"user/dotfiles"}, unknownGroup: {"user/notes"},
+		}},
+		{GroupByTopic, map[string][]string{
+			"kubernetes": {"kubernetes/kubectl"}, "cli": {"kubernetes/kubectl", "user/dotfiles"}, noTopicGroup: {"user/notes"},
+		}},
+		{GroupByOwner, map[string][]string{
+			"kubernetes": {"kubernetes/kubectl"}, "user": {"user/dotfiles", "user/notes"},
+		}},
+		{GroupByLicense, map[string][]string{
+			"Apache-2.0": {"kubernetes/kubectl"}, noLicenseGroup: {"user/dotfiles", "user/notes"},
+		}},
+		{GroupByStarredYear, map[string][]string{
+			"2023": {"kubernetes/kubectl"}, "2024": {"user/dotfiles"}, unknownGroup: {"user/notes"},
+		}},
+		{GroupByNone, map[string][]string{
+			allGroup: {"kubernetes/kubectl", "user/dotfiles", "user/notes"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			group, err := newGrouper(&Config{GroupBy: tt.by})
+			if err != nil {
+				t.Fatalf("newGrouper() returned an error: %v", err)
+			}
+			stars := groupStars(list, group)
+			got := make(map[string][]string)
+			for k, v := range stars {
+				for _, s := range v {
+					got[k] = append(got[k], s.NameWithOwner)
+				}
+				sort.Strings(got[k])
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("groupStars() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := newGrouper(&Config{GroupBy: "stars"}); err == nil {
+		t.Error("Expected an error for an unknown grouping")
+	}
+}
+
+func TestProcessStarsTotalCountsRepositoriesOnce(t *testing.T) {
+	list := []Star{
+		{NameWithOwner: "user/a", Topics: []string{"x", "y"}},
+		{NameWithOwner: "user/b", Topics: []string{"y"}},
+	}
+
+	stars, total, err := processStars(&Config{GroupBy: GroupByTopic}, list)
+	if err != nil {
+		t.Fatalf("processStars() returned an error: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("Expected total of 2, got %d", total)
+	}
+	if len(stars["y"]) != 2 || stars["y"][0].NameWithOwner != "user/a" {
+		t.Errorf("Expected both repositories sorted in topic 'y', got %+v", stars["y"])
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const HTMLFormat = "html"
+
+//go:embed html_template.html
+var htmlPage string
+
+// htmlRenderer renders the model with an html template, which escapes
+// descriptions and URLs for the context they appear in.
+type htmlRenderer struct {
+	t *htmltemplate.Template
+}
+
+func (r htmlRenderer) render(w io.Writer, data T) error {
+	return r.t.Execute(w, data)
+}
+
+// isHTMLTemplate reports whether a custom template is an html file.
+func isHTMLTemplate(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return (ext == ".html" || ext == ".htm") && exists(name)
+}
+
+// newHTMLRenderer parses the built-in page for the html format, or the given
+// html file otherwise.
+func newHTMLRenderer(tType string) (renderer, error) {
+	t := htmlPage
+	if tType != HTMLFormat {
+		b, err := os.ReadFile(tType)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read custom template: %v", err)
+		}
+		t = string(b)
+	}
+
+	temp, err := htmltemplate.New("page").Funcs(templateFuncs).Parse(t)
+	if err != nil {
+		return nil, err
+	}
+	return htmlRenderer{t: temp}, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	injectStart = "<!-- stargazer:start -->"
+	injectEnd   = "<!-- stargazer:end -->"
+)
+
+// injectList renders the list between the markers of an existing file and leaves
+// the rest of the file untouched.
+func injectList(path string, r renderer, data T) error {
+	if r == nil {
+		return errors.New("renderer not initialized")
+	}
+	if _, ok := r.(fileRenderer); ok {
+		return errors.New("the output format can't be injected into a file")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading file to inject into: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := r.render(&buf, data); err != nil {
+		return err
+	}
+
+	out, err := inject(content, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	changed, err := writeFile(path, out)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		logger.WithField("file", path).Info("Output unchanged, skipped writing")
+	}
+	return nil
+}
+
+// inject replaces everything between the start and end marker of content with list.
+func inject(content, list []byte) ([]byte, error) {
+	starts := bytes.Count(content, []byte(injectStart))
+	ends := bytes.Count(content, []byte(injectEnd))
+	switch {
+	case starts == 0 && ends == 0:
+		return nil, fmt.Errorf("markers not found, add %s and %s where the list should go", injectStart, injectEnd)
+	case starts != 1 || ends != 1:
+		return nil, fmt.Errorf("unbalanced markers, found %d %s and %d %s, expected one of each", starts, injectStart, ends, injectEnd)
+	}
+
+	start := bytes.Index(content, []byte(injectStart)) + len(injectStart)
+	end := bytes.Index(content, []byte(injectEnd))
+	if end < start {
+		return nil, fmt.Errorf("unbalanced markers, %s comes before %s", injectEnd, injectStart)
+	}
+
+	out := make([]byte, 0, len(content)+len(list))
+	out = append(out, content[:start]...)
+	out = append(out, '\n')
+	out = append(out, bytes.Trim(list, "\n")...)
+	out = append(out, '\n')
+	out = append(out, content[end:]...)
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInject(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+		err      string
+	}{
+		{
+			name:     "replaces content between markers",
+			content:  "# Hi\n\n" + injectStart + "\nold list\n" + injectEnd + "\n\nBye\n",
+			expected: "# Hi\n\n" + injectStart + "\nnew list\n" + injectEnd + "\n\nBye\n",
+		},
+		{
+			name:     "empty section",
+			content:  injectStart + injectEnd,
+			expected: injectStart + "\nnew list\n" + injectEnd,
+		},
+		{
+			name:    "missing markers",
+			content: "# Hi\n",
+			err:     "markers not found",
+		},
+		{
+			name:    "missing end marker",
+			content: injectStart + "\nold list\n",
+			err:     "unbalanced markers",
+		},
+		{
+			name:    "duplicate start marker",
+			content: injectStart + injectStart + injectEnd,
+			err:     "unbalanced markers",
+		},
+		{
+			name:    "end before start",
+			content: injectEnd + "\n" + injectStart,
+			err:     "comes before",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := inject([]byte(tt.content), []byte("new list\n"))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("inject() returned an error: %v", err)
+			}
+			if string(out) != tt.expected {
+				t.Errorf("Expected:\n%q\ngot:\n%q", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestInjectList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "README.md")
+	intro := "# My profile\n\nHand-written intro.\n\n"
+	if err := os.WriteFile(path, []byte(intro+injectStart+"\n"+injectEnd+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newRenderer(&Config{OutputFormat: "list"})
+	if err != nil {
+		t.Fatalf("newRenderer() returned an error: %v", err)
+	}
+	// injecting twice must give the same result
+	for i := 0; i < 2; i++ {
+		if err := injectList(path, r, testT()); err != nil {
+			t.Fatalf("injectList() returned an error: %v", err)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+	if !strings.HasPrefix(out, intro+injectStart+"\n# Awesome Starred Repos List") {
+		t.Errorf("Expected the intro to be kept:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "\n"+injectEnd+"\n") || strings.Count(out, "## Go") != 1 {
+		t.Errorf("Expected the list once between the markers:\n%s", out)
+	}
+
+	if err := injectList(filepath.Join(t.TempDir(), "missing.md"), r, testT()); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -3,19 +3,21 @@ package main
 
 import (
 	"bufio"
-	"fmt"
 	"os"
-	"sort"
 	"strings"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 var (
 	rootCmd     *cobra.Command
 	generateCmd *cobra.Command
+	fetchCmd    *cobra.Command
+	siteCmd     *cobra.Command
 )
 
 const (
@@ -23,17 +25,20 @@ const (
 	appDesc = "Creates awesome lists of your starred GitHub repositories"
 
 	defaultOutput      = "README.md"
+	defaultFullRefresh = 7
+	defaultMinDelta    = 10
 	defaultFormat      = "list"
 	defaultWithToc     = true
 	defaultWithStars   = true
 	defaultWithLicense = true
 	defaultWithBtt     = false
 
-	envUser   = "GITHUB_USER"
-	envToken  = "GITHUB_TOKEN"
-	envOutput = "OUTPUT_FILE"
-	envFormat = "OUTPUT_FORMAT"
-	envIgnore = "IGNORE_REPOS"
+	envUser    = "GITHUB_USER"
+	envToken   = "GITHUB_TOKEN"
+	envGraphQL = "GITHUB_GRAPHQL_URL"
+	envOutput  = "OUTPUT_FILE"
+	envFormat  = "OUTPUT_FORMAT"
+	envIgnore  = "IGNORE_REPOS"
 
 	envToc     = "WITH_TOC"
 	envStars   = "WITH_STARS"
@@ -68,6 +73,17 @@ func initConfig() {
 
 	if err := viper.ReadInConfig(); err == nil {
 		logger.Info("Using config file:", viper.ConfigFileUsed())
+		aliasConfigKeys()
+	}
+}
+
+// aliasConfigKeys makes the snake_case keys of the config file available under
+// the kebab-case names of the flags, which are used to read the configuration.
+func aliasConfigKeys() {
+	for _, k := range viper.AllKeys() {
+		if strings.Contains(k, "_") {
+			viper.SetDefault(strings.ReplaceAll(k, "_", "-"), viper.Get(k))
+		}
 	}
 }
 
@@ -80,6 +96,10 @@ func init() {
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// generate and site define flags of the same name, bind those of the running command
+			viper.BindPFlags(cmd.Flags())
+		},
 	}
 
 	generateCmd = &cobra.Command{
@@ -88,81 +108,287 @@ func init() {
 		Run:   runGenerate,
 	}
 
-	rootCmd.AddCommand(generateCmd)
+	fetchCmd = &cobra.Command{
+		Use:   "fetch",
+		Short: "Fetch the starred repositories and only refresh the snapshot",
+		Run:   runFetch,
+	}
+
+	siteCmd = &cobra.Command{
+		Use:   "site",
+		Short: "Generate a static site with a page per group and owner",
+		Run:   runSite,
+	}
+
+	rootCmd.AddCommand(generateCmd, fetchCmd, siteCmd)
+
+	rootCmd.PersistentFlags().StringP("github-user", "u", "", "github user name")
+	rootCmd.PersistentFlags().String("github-token", "", "github access token")
+	rootCmd.PersistentFlags().String("graphql-url", defaultGraphQLURL, "github GraphQL endpoint, e.g. https://github.example.com/api/graphql for GitHub Enterprise Server")
+	rootCmd.PersistentFlags().Int("rate-limit", 5, "number of API requests per second")
+	rootCmd.PersistentFlags().String("source", GithubSource, "where to get the stars from ["+strings.Join(sourceNames(), ", ")+"]")
+	rootCmd.PersistentFlags().String("source-file", "", "file to read the stars from, used by the file source")
+	rootCmd.PersistentFlags().String("snapshot-file", "", "file the fetched stars are saved to, e.g. .stargazer/snapshot.json")
+	rootCmd.PersistentFlags().Bool("incremental", false, "only fetch stars newer than the snapshot and merge them into it")
+	rootCmd.PersistentFlags().Int("full-refresh-days", defaultFullRefresh, "fetch all stars again in incremental mode after this many days, 0 to never")
 
 	generateCmd.Flags().StringP("output-file", "o", defaultOutput, "the file to create")
 	generateCmd.Flags().StringP("output-format", "f", defaultFormat, "the format of the output ["+strings.Join(availableFormats, ", ")+"]")
-	generateCmd.Flags().StringP("github-user", "u", "", "github user name")
-	generateCmd.Flags().String("github-token", "", "github access token")
-	generateCmd.Flags().Int("rate-limit", 5, "number of API requests per second")
-	generateCmd.Flags().StringSliceP("ignore", "i", []string{}, "repositories to ignore (flag can be specified multiple times)")
-	generateCmd.Flags().BoolP("test", "t", false, "just put out some test data")
-	generateCmd.Flags().Bool("with-toc", true, "print table of contents")
-	generateCmd.Flags().Bool("with-stars", true, "print starcount of repositories")
-	generateCmd.Flags().Bool("with-license", true, "print license of repositories")
-	generateCmd.Flags().Bool("with-back-to-top", false, "generate 'back to top' links for each language")
-
-	viper.BindPFlags(generateCmd.Flags())
+	generateCmd.Flags().String("list-template-dir", "", "directory with a main template and partials replacing those of the template ["+strings.Join(templatePartials, ", ")+"]")
+	generateCmd.Flags().Bool("inject", false, "replace only the content between the "+injectStart+" and "+injectEnd+" markers of the output file")
+	generateCmd.Flags().Bool("split", false, "write a file per group to --split-dir and an index to the output file")
+	generateCmd.Flags().String("split-dir", defaultSplitDir, "directory of the files per group, relative to the output file")
+	generateCmd.Flags().StringSlice("columns", []string{}, "columns of the csv and tsv formats, default "+strings.Join(defaultCSVColumns, ","))
+	generateCmd.Flags().Int("feed-limit", defaultFeedLimit, "maximum number of entries of the atom and rss formats, 0 for no limit")
+	generateCmd.Flags().Int("feed-max-age", 0, "maximum age in days of entries of the atom and rss formats, 0 for no limit")
+	generateCmd.Flags().String("changelog-file", "", "file to prepend the changes since the last run to, e.g. CHANGELOG.md")
+	generateCmd.Flags().Int("changelog-min-delta", defaultMinDelta, "minimum change of the star count of a repository to be reported")
+	addListFlags(generateCmd.Flags())
+
+	generateCmd.Flags().Bool("dry-run", false, "print a diff of the changes to the output files instead of writing them")
+	generateCmd.Flags().Bool("check", false, "exit with an error if the output files are out of date, without writing them")
+
+	siteCmd.Flags().StringP("output-dir", "d", defaultSiteDir, "the directory to write the pages to")
+	siteCmd.Flags().String("template-dir", "", "directory with templates replacing the built-in pages ["+strings.Join(sitePageTemplates, ", ")+"]")
+	siteCmd.Flags().Int("recent-limit", defaultRecentLimit, "number of repositories on the recent page, 0 for no limit")
+	siteCmd.Flags().Bool("dry-run", false, "print a diff of the changes to the pages instead of writing them")
+	siteCmd.Flags().Bool("check", false, "exit with an error if the pages are out of date, without writing them")
+	addListFlags(siteCmd.Flags())
+
+	viper.BindPFlags(rootCmd.PersistentFlags())
+	viper.BindEnv("graphql-url", envGraphQL)
+}
+
+// addListFlags adds the flags selecting and arranging the repositories, shared by
+// generate and site.
+func addListFlags(flags *pflag.FlagSet) {
+	flags.StringSliceP("ignore", "i", []string{}, "repositories to ignore (flag can be specified multiple times)")
+	flags.String("filter", "", "only keep repositories matching the expression, e.g. 'stars >= 100 && !archived'")
+	flags.StringSlice("exclude", []string{}, "glob or /regexp/ of repositories (owner/repo) to exclude (flag can be specified multiple times)")
+	flags.String("description-emoji", EmojiKeep, "how to handle :shortcode: emoji in descriptions ["+strings.Join(availableEmojiModes, ", ")+"]")
+	flags.Int("description-max-length", 0, "shorten longer descriptions, 0 for no limit")
+	flags.BoolP("test", "t", false, "just put out some test data (same as --source test)")
+	flags.Bool("from-snapshot", false, "render the stars from the snapshot file, without token and network access")
+	flags.String("group-by", "", "how to group the repositories ["+strings.Join(availableGroupings, ", ")+"] (default category if categories are configured, else language)")
+	flags.String("sort", SortByName, "order of the repositories in a group ["+strings.Join(availableSorts, ", ")+"], optionally with :asc or :desc")
+	flags.String("group-sort", GroupSortByName, "order of the groups ["+strings.Join(availableGroupSorts, ", ")+"], optionally with :asc or :desc")
+	flags.StringSlice("group-order", []string{}, "order of the groups for --group-sort custom")
+	flags.Bool("with-toc", true, "print table of contents")
+	flags.Bool("with-stars", true, "print starcount of repositories")
+	flags.Bool("with-license", true, "print license of repositories")
+	flags.Bool("with-back-to-top", false, "generate 'back to top' links for each language")
+}
+
+// newConfig builds the configuration from flags, environment and config file.
+func newConfig() *Config {
+	var categories []Category
+	if err := viper.UnmarshalKey("categories", &categories); err != nil {
+		logger.WithError(err).Fatal("Failed to parse categories")
+	}
+	var outputs []Output
+	if err := viper.UnmarshalKey("outputs", &outputs); err != nil {
+		logger.WithError(err).Fatal("Failed to parse outputs")
+	}
+
+	return &Config{
+		OutputFile:           viper.GetString("output-file"),
+		OutputFormat:         viper.GetString("output-format"),
+		Outputs:              outputs,
+		Split:                viper.GetBool("split"),
+		Inject:               viper.GetBool("inject"),
+		DryRun:               viper.GetBool("dry-run"),
+		Check:                viper.GetBool("check"),
+		SplitDir:             viper.GetString("split-dir"),
+		OutputDir:            viper.GetString("output-dir"),
+		TemplateDir:          viper.GetString("template-dir"),
+		ListTemplateDir:      viper.GetString("list-template-dir"),
+		RecentLimit:          viper.GetInt("recent-limit"),
+		Columns:              viper.GetStringSlice("columns"),
+		FeedLimit:            viper.GetInt("feed-limit"),
+		FeedMaxAgeDays:       viper.GetInt("feed-max-age"),
+		GithubUser:           viper.GetString("github-user"),
+		GithubToken:          viper.GetString("github-token"),
+		GraphQLURL:           viper.GetString("graphql-url"),
+		IgnoreRepos:          viper.GetStringSlice("ignore"),
+		Filter:               viper.GetString("filter"),
+		Exclude:              viper.GetStringSlice("exclude"),
+		DescriptionEmoji:     viper.GetString("description-emoji"),
+		DescriptionMaxLength: viper.GetInt("description-max-length"),
+		Source:               viper.GetString("source"),
+		SourceFile:           viper.GetString("source-file"),
+		SnapshotFile:         viper.GetString("snapshot-file"),
+		FromSnapshot:         viper.GetBool("from-snapshot"),
+		Incremental:          viper.GetBool("incremental"),
+		FullRefreshDays:      viper.GetInt("full-refresh-days"),
+		ChangelogFile:        viper.GetString("changelog-file"),
+		ChangelogMinDelta:    viper.GetInt("changelog-min-delta"),
+		GroupBy:              viper.GetString("group-by"),
+		Categories:           categories,
+		FallbackCategory:     viper.GetString("fallback-category"),
+		Sort:                 viper.GetString("sort"),
+		GroupSort:            viper.GetString("group-sort"),
+		GroupOrder:           viper.GetStringSlice("group-order"),
+		Test:                 viper.GetBool("test"),
+		WithTOC:              viper.GetBool("with-toc"),
+		WithStars:            viper.GetBool("with-stars"),
+		WithLicense:          viper.GetBool("with-license"),
+		WithBackToTop:        viper.GetBool("with-back-to-top"),
+		RateLimit:            viper.GetInt("rate-limit"),
+	}
 }
 
 func runGenerate(cmd *cobra.Command, args []string) {
-	config := &Config{
-		OutputFile:    viper.GetString("output-file"),
-		OutputFormat:  viper.GetString("output-format"),
-		GithubUser:    viper.GetString("github-user"),
-		GithubToken:   viper.GetString("github-token"),
-		IgnoreRepos:   viper.GetStringSlice("ignore"),
-		Test:          viper.GetBool("test"),
-		WithTOC:       viper.GetBool("with-toc"),
-		WithStars:     viper.GetBool("with-stars"),
-		WithLicense:   viper.GetBool("with-license"),
-		WithBackToTop: viper.GetBool("with-back-to-top"),
-		RateLimit:     viper.GetInt("rate-limit"),
+	config := newConfig()
+
+	ignored = config.IgnoreRepos
+	dryRun = config.DryRun || config.Check
+
+	outputs, err := outputConfigs(config)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid outputs")
+	}
+	renderers := make([]renderer, len(outputs))
+	for i, o := range outputs {
+		if renderers[i], err = newRenderer(o); err != nil {
+			logger.WithError(err).WithField("output", o.OutputFile).Fatal("Failed to initialize template")
+		}
 	}
 
-	if config.GithubToken == "" && !config.Test {
-		logger.Fatal("GitHub token is required. Please provide a valid token.")
+	snap, prev, err := loadStars(config)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to fetch stars")
 	}
 
-	if err := initTemplate(config.OutputFormat); err != nil {
-		logger.WithError(err).Fatal("Failed to initialize template")
+	for i, o := range outputs {
+		total, err := renderOutput(o, renderers[i], snap, prev)
+		if err != nil {
+			logger.WithError(err).WithField("output", o.OutputFile).Fatal("Failed to generate list")
+		}
+		logger.WithFields(logrus.Fields{"output": o.OutputFile, "total_repositories": total}).Info("Successfully generated starred repositories list")
 	}
 
-	stars, total, err := fetchAndProcessStars(config)
+	if config.ChangelogFile != "" {
+		changes, err := changesSince(config, prev, snap)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to compare snapshots")
+		}
+		// the changelog lists the repositories as markdown list items
+		san, err := newSanitizer(config, escapeListItem)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to sanitize descriptions")
+		}
+		if err := writeChangelog(config.ChangelogFile, san.changes(changes)); err != nil {
+			logger.WithError(err).Fatal("Failed to write changelog")
+		}
+	}
+
+	if err := reportChanges(config, os.Stdout); err != nil {
+		logger.WithError(err).Fatal("Check failed")
+	}
+}
+
+func runFetch(cmd *cobra.Command, args []string) {
+	config := newConfig()
+
+	if config.SnapshotFile == "" {
+		logger.Fatal("A snapshot file is required. Please provide one with --snapshot-file.")
+	}
+
+	snap, _, err := refreshSnapshot(config)
 	if err != nil {
-		logger.WithError(err).Fatal("Failed to fetch and process stars")
+		logger.WithError(err).Fatal("Failed to refresh snapshot")
 	}
 
-	err = writeList(config.OutputFile, stars, total, config.WithTOC, config.WithLicense, config.WithStars, config.WithBackToTop)
+	logger.WithField("total_repositories", len(snap.Stars)).Info("Successfully refreshed snapshot ", config.SnapshotFile)
+}
+
+func runSite(cmd *cobra.Command, args []string) {
+	config := newConfig()
+
+	ignored = config.IgnoreRepos
+	dryRun = config.DryRun || config.Check
+
+	t, err := initSiteTemplates(config.TemplateDir)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize templates")
+	}
+
+	snap, _, err := loadStars(config)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to fetch stars")
+	}
+
+	list, err := filterStars(config, snap.Stars)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to filter stars")
+	}
+	stars, total, err := processStars(config, list)
 	if err != nil {
-		logger.WithError(err).Fatal("Failed to write list")
+		logger.WithError(err).Fatal("Failed to process stars")
+	}
+	san, err := newSanitizer(config, escapeHTML)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to sanitize descriptions")
+	}
+	stars, list = san.groups(stars), san.stars(list)
+
+	data, err := newT(config, snap, stars, total, nil)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to sort groups")
+	}
+
+	pages, err := sitePages(config, data, list)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to build pages")
+	}
+	if err := writeSite(config.OutputDir, t, pages); err != nil {
+		logger.WithError(err).Fatal("Failed to write site")
 	}
 
-	logger.WithField("total_repositories", total).Info("Successfully generated starred repositories list")
+	if err := reportChanges(config, os.Stdout); err != nil {
+		logger.WithError(err).Fatal("Check failed")
+	}
+
+	logger.WithField("pages", len(pages)).Info("Successfully generated site in ", config.OutputDir)
 }
 
-// fetchAndProcessStars retrieves and processes starred repositories based on the provided configuration.
-func fetchAndProcessStars(config *Config) (map[string][]Star, int, error) {
-	var stars map[string][]Star
-	var total int
-	var err error
-
-	if config.Test {
-		stars, total = testStars()
-	} else {
-		if stars, total, err = DefaultFetchStars(config.GithubUser, config.GithubToken, config.RateLimit); err != nil {
-			return nil, 0, fmt.Errorf("failed to fetch stars: %v", err)
+// filterStars drops the ignored and excluded repositories and those not matching
+// the filter expression.
+func filterStars(config *Config, list []Star) ([]Star, error) {
+	f, err := newStarFilter(config.Filter, config.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]Star, 0, len(list))
+	for _, s := range list {
+		if isIgnored(s.NameWithOwner) {
+			continue
+		}
+		ok, err := f.keep(s)
+		if err != nil {
+			return nil, err
 		}
+		if ok {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered, nil
+}
+
+// processStars groups and sorts the stars as configured.
+func processStars(config *Config, list []Star) (map[string][]Star, int, error) {
+	group, err := newGrouper(config)
+	if err != nil {
+		return nil, 0, err
 	}
+	stars := groupStars(list, group)
 
-	for k, v := range stars {
-		sort.Slice(v, func(i, j int) bool {
-			return strings.ToLower(v[i].NameWithOwner) < strings.ToLower(v[j].NameWithOwner)
-		})
-		stars[k] = v
+	if err := sortStars(stars, config.Sort); err != nil {
+		return nil, 0, err
 	}
 
-	return stars, total, nil
+	return stars, len(list), nil
 }
 
 // isIgnored checks if a repository name is in the ignored list.
@@ -179,71 +405,61 @@ func isIgnored(name string) bool {
 }
 
 // testStars generates test data for starred repositories.
-func testStars() (stars map[string][]Star, total int) {
-	stars = make(map[string][]Star)
-	stars["go"] = make([]Star, 1)
-	s := Star{
-		Url:           "https://github.com/jmelfi/stargazer",
-		Name:          "stargazer",
-		NameWithOwner: "jmelfi/stargazer",
-		Description:   "Creates awesome lists of your starred repositories",
-		License:       "MIT License",
-		Stars:         1,
-		Archived:      false,
-		StarredAt:     time.Now(),
-	}
-	if !isIgnored(s.NameWithOwner) {
-		stars["go"][0] = s
-	}
-	stars["markdown"] = make([]Star, 1)
-	s = Star{
-		Url:           "https://github.com/jmelfi/stars",
-		Name:          "stars",
-		NameWithOwner: "jmelfi/stars",
-		Description:   "A list of awesome repositories I starred",
-		License:       "MIT License",
-		Stars:         1,
-		Archived:      false,
-		StarredAt:     time.Now(),
-	}
-	if !isIgnored(s.NameWithOwner) {
-		stars["markdown"][0] = s
-	}
-
-	stars["C#"] = make([]Star, 0)
-	stars["C++"] = make([]Star, 0)
-
-	stars["C#"] = append(stars["C#"], Star{
-		Url:           "https://github.com/jmelfi/test",
-		Name:          "test",
-		NameWithOwner: "jmelfi/test",
-		Description:   "",
-		License:       "MIT License",
-		Stars:         1,
-		StarredAt:     time.Now(),
-	})
-	stars["C++"] = append(stars["C++"], Star{
-		Url:           "https://github.com/jmelfi/test_2",
-		Name:          "test_2",
-		NameWithOwner: "rverst/test_2",
-		Description:   "Some description",
-		License:       "",
-		Stars:         1,
-		StarredAt:     time.Now(),
-	})
-
-	stars["C#"] = append(stars["C#"], Star{
-		Url:           "https://github.com/jmelfi/test_3",
-		Name:          "test_3",
-		NameWithOwner: "jmelfi/test_3",
-		Description:   "",
-		License:       "",
-		Stars:         1,
-		StarredAt:     time.Now(),
-	})
-
-	total = 4
-	return
+func testStars() []Star {
+	return []Star{
+		{
+			Url:           "https://github.com/jmelfi/stargazer",
+			Name:          "stargazer",
+			NameWithOwner: "jmelfi/stargazer",
+			Description:   "Creates awesome lists of your starred repositories",
+			Language:      "go",
+			License:       "MIT License",
+			Stars:         1,
+			Archived:      false,
+			StarredAt:     time.Now(),
+		},
+		{
+			Url:           "https://github.com/jmelfi/stars",
+			Name:          "stars",
+			NameWithOwner: "jmelfi/stars",
+			Description:   "A list of awesome repositories I starred",
+			Language:      "markdown",
+			License:       "MIT License",
+			Stars:         1,
+			Archived:      false,
+			StarredAt:     time.Now(),
+		},
+		{
+			Url:           "https://github.com/jmelfi/test",
+			Name:          "test",
+			NameWithOwner: "jmelfi/test",
+			Description:   "",
+			Language:      "C#",
+			License:       "MIT License",
+			Stars:         1,
+			StarredAt:     time.Now(),
+		},
+		{
+			Url:           "https://github.com/jmelfi/test_2",
+			Name:          "test_2",
+			NameWithOwner: "rverst/test_2",
+			Description:   "Some description",
+			Language:      "C++",
+			License:       "",
+			Stars:         1,
+			StarredAt:     time.Now(),
+		},
+		{
+			Url:           "https://github.com/jmelfi/test_3",
+			Name:          "test_3",
+			NameWithOwner: "jmelfi/test_3",
+			Description:   "",
+			Language:      "C#",
+			License:       "",
+			Stars:         1,
+			StarredAt:     time.Now(),
+		},
+	}
 }
 
 // getEnv retrieves environment variables with fallback to .env file and default values.
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+const OPMLFormat = "opml"
+
+// opmlRenderer writes an OPML outline with the release feeds of the repositories,
+// grouped like the list, ready to be imported into a feed reader.
+type opmlRenderer struct{}
+
+type opml struct {
+	XMLName xml.Name  `xml:"opml"`
+	Version string    `xml:"version,attr"`
+	Head    opmlHead  `xml:"head"`
+	Body    []outline `xml:"body>outline"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	Docs        string `xml:"docs"`
+}
+
+type outline struct {
+	Type        string    `xml:"type,attr,omitempty"`
+	Text        string    `xml:"text,attr"`
+	Title       string    `xml:"title,attr,omitempty"`
+	XMLUrl      string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLUrl     string    `xml:"htmlUrl,attr,omitempty"`
+	Description string    `xml:"description,attr,omitempty"`
+	Outlines    []outline `xml:"outline"`
+}
+
+func (opmlRenderer) render(w io.Writer, data T) error {
+	title := "Releases of starred repositories"
+	if data.User != "" {
+		title += " of " + data.User
+	}
+
+	doc := opml{
+		Version: "2.0",
+		Head:    opmlHead{Title: title, Docs: "http://opml.org/spec2.opml"},
+	}
+	if !data.FetchedAt.IsZero() {
+		doc.Head.DateCreated = data.FetchedAt.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, k := range data.Keys {
+		group := outline{Text: k, Title: k}
+		for _, s := range data.Stars[k] {
+			group.Outlines = append(group.Outlines, outline{
+				Type:        "rss",
+				Text:        s.NameWithOwner,
+				Title:       s.NameWithOwner,
+				XMLUrl:      releasesFeed(s.Url),
+				HTMLUrl:     s.Url,
+				Description: s.Description,
+			})
+		}
+		doc.Body = append(doc.Body, group)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// releasesFeed returns the Atom feed of the releases of a repository.
+func releasesFeed(repoURL string) string {
+	return strings.TrimSuffix(repoURL, "/") + "/releases.atom"
+}
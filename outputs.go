@@ -0,0 +1,214 @@
+package main
+
+import "fmt"
+
+// Output is one of several outputs of a generate run, all rendered from the same
+// fetch. Fields that aren't set are taken from the configuration.
+type Output struct {
+	Path                 string   `yaml:"path" mapstructure:"path"`                                               // Path of the output file
+	Format               string   `yaml:"format,omitempty" mapstructure:"format"`                                 // Format or template of the output
+	ListTemplateDir      string   `yaml:"list_template_dir,omitempty" mapstructure:"list_template_dir"`           // Directory with a main template and partials
+	Columns              []string `yaml:"columns,omitempty" mapstructure:"columns"`                               // Columns of the csv and tsv formats
+	FeedLimit            *int     `yaml:"feed_limit,omitempty" mapstructure:"feed_limit"`                         // Maximum number of entries of the atom and rss formats
+	FeedMaxAge           *int     `yaml:"feed_max_age,omitempty" mapstructure:"feed_max_age"`                     // Maximum age in days of entries of the atom and rss formats
+	Split                *bool    `yaml:"split,omitempty" mapstructure:"split"`                                   // Whether to write a file per group and an index
+	SplitDir             string   `yaml:"split_dir,omitempty" mapstructure:"split_dir"`                           // Directory of the files per group
+	Inject               *bool    `yaml:"inject,omitempty" mapstructure:"inject"`                                 // Whether to replace only the content between the markers
+	Filter               string   `yaml:"filter,omitempty" mapstructure:"filter"`                                 // Expression repositories have to match
+	Exclude              []string `yaml:"exclude,omitempty" mapstructure:"exclude"`                               // Glob or /regexp/ patterns of repositories to exclude
+	DescriptionEmoji     string   `yaml:"description_emoji,omitempty" mapstructure:"description_emoji"`           // How to handle :shortcode: emoji in descriptions
+	DescriptionMaxLength *int     `yaml:"description_max_length,omitempty" mapstructure:"description_max_length"` // Maximum length of descriptions
+	GroupBy              string   `yaml:"group_by,omitempty" mapstructure:"group_by"`                             // How to group the repositories
+	Sort                 string   `yaml:"sort,omitempty" mapstructure:"sort"`                                     // Order of the repositories in a group
+	GroupSort            string   `yaml:"group_sort,omitempty" mapstructure:"group_sort"`                         // Order of the groups
+	GroupOrder           []string `yaml:"group_order,omitempty" mapstructure:"group_order"`                       // Order of the groups for the custom group sort
+	WithTOC              *bool    `yaml:"with_toc,omitempty" mapstructure:"with_toc"`                             // Whether to include a table of contents
+	WithStars            *bool    `yaml:"with_stars,omitempty" mapstructure:"with_stars"`                         // Whether to include star counts
+	WithLicense          *bool    `yaml:"with_license,omitempty" mapstructure:"with_license"`                     // Whether to include license information
+	WithBackToTop        *bool    `yaml:"with_back_to_top,omitempty" mapstructure:"with_back_to_top"`             // Whether to include "back to top" links
+}
+
+// outputConfigs returns the configuration of every output. Without outputs the
+// configuration itself is the only output.
+func outputConfigs(config *Config) ([]*Config, error) {
+	if len(config.Outputs) == 0 {
+		return []*Config{config}, nil
+	}
+
+	configs := make([]*Config, 0, len(config.Outputs))
+	paths := make(map[string]bool, len(config.Outputs))
+	for i, o := range config.Outputs {
+		if o.Path == "" {
+			return nil, fmt.Errorf("output %d has no path", i+1)
+		}
+		if paths[o.Path] {
+			return nil, fmt.Errorf("output %s is configured more than once", o.Path)
+		}
+		paths[o.Path] = true
+
+		configs = append(configs, o.apply(config))
+	}
+	return configs, nil
+}
+
+// apply returns a copy of the configuration with the fields set in the output.
+func (o Output) apply(config *Config) *Config {
+	c := *config
+	c.Outputs = nil
+	c.OutputFile = o.Path
+
+	if o.Format != "" {
+		c.OutputFormat = o.Format
+	}
+	if o.ListTemplateDir != "" {
+		c.ListTemplateDir = o.ListTemplateDir
+	}
+	if o.Columns != nil {
+		c.Columns = o.Columns
+	}
+	if o.FeedLimit != nil {
+		c.FeedLimit = *o.FeedLimit
+	}
+	if o.FeedMaxAge != nil {
+		c.FeedMaxAgeDays = *o.FeedMaxAge
+	}
+	if o.Split != nil {
+		c.Split = *o.Split
+	}
+	if o.SplitDir != "" {
+		c.SplitDir = o.SplitDir
+	}
+	if o.Inject != nil {
+		c.Inject = *o.Inject
+	}
+	if o.Filter != "" {
+		c.Filter = o.Filter
+	}
+	if o.Exclude != nil {
+		c.Exclude = o.Exclude
+	}
+	if o.DescriptionEmoji != "" {
+		c.DescriptionEmoji = o.DescriptionEmoji
+	}
+	if o.DescriptionMaxLength != nil {
+		c.DescriptionMaxLength = *o.DescriptionMaxLength
+	}
+	if o.GroupBy != "" {
+		c.GroupBy = o.GroupBy
+	}
+	if o.Sort != "" {
+		c.Sort = o.Sort
+	}
+	if o.GroupSort != "" {
+		c.GroupSort = o.GroupSort
+	}
+	if o.GroupOrder != nil {
+		c.GroupOrder = o.GroupOrder
+	}
+	if o.WithTOC != nil {
+		c.WithTOC = *o.WithTOC
+	}
+	if o.WithStars != nil {
+		c.WithStars = *o.WithStars
+	}
+	if o.WithLicense != nil {
+		c.WithLicense = *o.WithLicense
+	}
+	if o.WithBackToTop != nil {
+		c.WithBackToTop = *o.WithBackToTop
+	}
+
+	return &c
+}
+
+// renderOutput renders the stars of the snapshot to the output of the configuration
+// and returns the number of repositories listed.
+func renderOutput(config *Config, r renderer, snap, prev *Snapshot) (int, error) {
+	list, err := filterStars(config, snap.Stars)
+	if err != nil {
+		return 0, fmt.Errorf("error filtering stars: %v", err)
+	}
+	stars, total, err := processStars(config, list)
+	if err != nil {
+		return 0, fmt.Errorf("error processing stars: %v", err)
+	}
+	san, err := newSanitizer(config, escapeContextOf(r))
+	if err != nil {
+		return 0, err
+	}
+	stars = san.groups(stars)
+
+	changes, err := changesSince(config, prev, snap)
+	if err != nil {
+		return 0, err
+	}
+	changes = san.changes(changes)
+
+	data, err := newT(config, snap, stars, total, changes)
+	if err != nil {
+		return 0, fmt.Errorf("error sorting groups: %v", err)
+	}
+
+	switch {
+	case config.Split:
+		err = writeSplit(config, r, data)
+	case config.Inject:
+		err = injectList(config.OutputFile, r, data)
+	default:
+		err = writeList(config.OutputFile, r, data)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error writing list: %v", err)
+	}
+
+	return total, nil
+}
+
+// changesSince returns the changes of the listed stars since the previous
+// snapshot, nil without one. The unfiltered snapshots are compared, so a
+// repository that only stops matching the filter isn't reported as unstarred.
+func changesSince(config *Config, prev, snap *Snapshot) (*Changelog, error) {
+	if prev == nil {
+		return nil, nil
+	}
+
+	c := diffSnapshots(prev, snap, config.ChangelogMinDelta)
+
+	// removed repositories are listed as they were in the previous snapshot
+	removed, err := filterStars(config, c.Removed)
+	if err != nil {
+		return nil, fmt.Errorf("error filtering stars: %v", err)
+	}
+	list, err := filterStars(config, snap.Stars)
+	if err != nil {
+		return nil, fmt.Errorf("error filtering stars: %v", err)
+	}
+	listed := make(map[string]bool, len(list))
+	for _, s := range list {
+		listed[starKey(s)] = true
+	}
+
+	filtered := &Changelog{Since: c.Since, Until: c.Until}
+	for _, s := range c.Added {
+		if listed[starKey(s)] {
+			filtered.Added = append(filtered.Added, s)
+		}
+	}
+	filtered.Removed = append(filtered.Removed, removed...)
+	for _, r := range c.Renamed {
+		if listed[starKey(r.Star)] {
+			filtered.Renamed = append(filtered.Renamed, r)
+		}
+	}
+	for _, s := range c.Archived {
+		if listed[starKey(s)] {
+			filtered.Archived = append(filtered.Archived, s)
+		}
+	}
+	for _, d := range c.StarDeltas {
+		if listed[starKey(d.Star)] {
+			filtered.StarDeltas = append(filtered.StarDeltas, d)
+		}
+	}
+	return filtered, nil
+}
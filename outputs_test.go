@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOutputConfigs(t *testing.T) {
+	off := false
+	limit := 10
+	config := &Config{
+		OutputFile:   "README.md",
+		OutputFormat: "list",
+		Filter:       "stars > 1",
+		GroupBy:      GroupByLanguage,
+		FeedLimit:    defaultFeedLimit,
+		WithTOC:      true,
+		WithStars:    true,
+		Outputs: []Output{
+			{Path: "README.md"},
+			{Path: "stars.json", Format: JSONFormat, Filter: "true", GroupBy: GroupByNone},
+			{Path: "feed.xml", Format: AtomFormat, FeedLimit: &limit, WithStars: &off},
+		},
+	}
+
+	configs, err := outputConfigs(config)
+	if err != nil {
+		t.Fatalf("outputConfigs() returned an error: %v", err)
+	}
+
+	tests := []struct {
+		path      string
+		format    string
+		filter    string
+		groupBy   string
+		feedLimit int
+		withStars bool
+	}{
+		{"README.md", "list", "stars > 1", GroupByLanguage, defaultFeedLimit, true},
+		{"stars.json", JSONFormat, "true", GroupByNone, defaultFeedLimit, true},
+		{"feed.xml", AtomFormat, "stars > 1", GroupByLanguage, 10, false},
+	}
+	if len(configs) != len(tests) {
+		t.Fatalf("Expected %d configs, got %d", len(tests), len(configs))
+	}
+	for i, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			c := configs[i]
+			if c.OutputFile != tt.path || c.OutputFormat != tt.format || c.Filter != tt.filter || c.GroupBy != tt.groupBy {
+				t.Errorf("Unexpected config %+v", c)
+			}
+			if c.FeedLimit != tt.feedLimit || c.WithStars != tt.withStars || !c.WithTOC {
+				t.Errorf("Unexpected settings %+v", c)
+			}
+			if c.Outputs != nil {
+				t.Error("Expected the outputs to be cleared")
+			}
+		})
+	}
+	if !config.WithStars || config.OutputFile != "README.md" {
+		t.Error("Expected the configuration not to be modified")
+	}
+
+	single, err := outputConfigs(&Config{OutputFile: "README.md"})
+	if err != nil || len(single) != 1 || single[0].OutputFile != "README.md" {
+		t.Errorf("Expected the configuration as the only output, got %v, %v", single, err)
+	}
+
+	for _, outputs := range [][]Output{{{Format: JSONFormat}}, {{Path: "a.md"}, {Path: "a.md"}}} {
+		if _, err := outputConfigs(&Config{Outputs: outputs}); err == nil {
+			t.Errorf("Expected an error for outputs %+v", outputs)
+		}
+	}
+}
+
+func TestRenderOutputs(t *testing.T) {
+	dir := t.TempDir()
+	config := &Config{
+		OutputFormat: "list",
+		Sort:         SortByName,
+		WithStars:    true,
+		Outputs: []Output{
+			{Path: filepath.Join(dir, "README.md")},
+			{Path: filepath.Join(dir, "stars.json"), Format: JSONFormat, Filter: `language == "Go"`},
+		},
+	}
+	snap := NewSnapshot("testuser", []Star{
+		{Url: "https://github.com/user/repo1", NameWithOwner: "user/repo1", Language: "Go", Stars: 3},
+		{Url: "https://github.com/user/repo2", NameWithOwner: "user/repo2", Language: "Rust", Stars: 5},
+	})
+
+	configs, err := outputConfigs(config)
+	if err != nil {
+		t.Fatalf("outputConfigs() returned an error: %v", err)
+	}
+	for _, c := range configs {
+		r, err := newRenderer(c)
+		if err != nil {
+			t.Fatalf("newRenderer() returned an error: %v", err)
+		}
+		if _, err := renderOutput(c, r, snap, nil); err != nil {
+			t.Fatalf("renderOutput() returned an error: %v", err)
+		}
+	}
+
+	readme := string(mustReadFile(t, filepath.Join(dir, "README.md")))
+	if !strings.Contains(readme, "user/repo1") || !strings.Contains(readme, "user/repo2") {
+		t.Errorf("Expected all repositories in the list:\n%s", readme)
+	}
+
+	var export Export
+	if err := json.Unmarshal(mustReadFile(t, filepath.Join(dir, "stars.json")), &export); err != nil {
+		t.Fatalf("Failed to parse export: %v", err)
+	}
+	if export.Total != 1 || len(export.Stars["Go"]) != 1 {
+		t.Errorf("Expected only the filtered repository in the export, got %+v", export)
+	}
+}
+
+func TestRenderOutputRawDescriptions(t *testing.T) {
+	const description = "line one\nline two :rocket: and a long tail"
+	dir := t.TempDir()
+	snap := NewSnapshot("testuser", []Star{
+		{Url: "https://github.com/user/repo1", NameWithOwner: "user/repo1", Language: "Go", Description: description},
+	})
+
+	tests := []struct {
+		format string
+		parse  func(t *testing.T, b []byte) string
+	}{
+		{CSVFormat, func(t *testing.T, b []byte) string {
+			rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+			if err != nil || len(rows) != 2 {
+				t.Fatalf("Failed to parse csv: %v\n%s", err, b)
+			}
+			return rows[1][slices.Index(rows[0], "description")]
+		}},
+		{JSONFormat, func(t *testing.T, b []byte) string {
+			var export Export
+			if err := json.Unmarshal(b, &export); err != nil || len(export.Stars["Go"]) != 1 {
+				t.Fatalf("Failed to parse json: %v\n%s", err, b)
+			}
+			return export.Stars["Go"][0].Description
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			config := &Config{
+				OutputFile:           filepath.Join(dir, "stars."+tt.format),
+				OutputFormat:         tt.format,
+				DescriptionEmoji:     EmojiStrip,
+				DescriptionMaxLength: 10,
+			}
+			r, err := newRenderer(config)
+			if err != nil {
+				t.Fatalf("newRenderer() returned an error: %v", err)
+			}
+			if _, err := renderOutput(config, r, snap, nil); err != nil {
+				t.Fatalf("renderOutput() returned an error: %v", err)
+			}
+			if got := tt.parse(t, mustReadFile(t, config.OutputFile)); got != description {
+				t.Errorf("Expected the description as it is, got %q", got)
+			}
+		})
+	}
+}
+
+func TestRenderOutputSQLiteIgnored(t *testing.T) {
+	config := &Config{
+		OutputFile:   filepath.Join(t.TempDir(), "stars.db"),
+		OutputFormat: SQLiteFormat,
+		Filter:       `language == "Go"`,
+	}
+	snap := NewSnapshot("testuser", []Star{
+		{Url: "https://github.com/user/repo1", NameWithOwner: "user/repo1", Language: "Go"},
+		{Url: "https://github.com/user/ignored", NameWithOwner: "user/ignored", Language: "Go"},
+		{Url: "https://github.com/user/filtered", NameWithOwner: "user/filtered", Language: "Rust"},
+	})
+	ignored = []string{"user/ignored"}
+	defer func() { ignored = nil }()
+
+	r, err := newRenderer(config)
+	if err != nil {
+		t.Fatalf("newRenderer() returned an error: %v", err)
+	}
+	if _, err := renderOutput(config, r, snap, nil); err != nil {
+		t.Fatalf("renderOutput() returned an error: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", config.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT name_with_owner FROM repositories")
+	if err != nil {
+		t.Fatalf("Failed to query repositories: %v", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Failed to scan repository: %v", err)
+		}
+		names = append(names, name)
+	}
+	if !slices.Equal(names, []string{"user/repo1"}) {
+		t.Errorf("Expected only the listed repository, got %v", names)
+	}
+}
+
+func TestChangesSince(t *testing.T) {
+	prev := NewSnapshot("testuser", []Star{
+		{ID: "1", Url: "https://github.com/a/b", NameWithOwner: "a/b", Stars: 100},
+		{ID: "2", Url: "https://github.com/c/d", NameWithOwner: "c/d", Stars: 200},
+		{ID: "3", Url: "https://github.com/x/y", NameWithOwner: "x/y", Stars: 300},
+		{ID: "4", Url: "https://github.com/low/gone", NameWithOwner: "low/gone", Stars: 1},
+	})
+	snap := NewSnapshot("testuser", []Star{
+		{ID: "1", Url: "https://github.com/a/b", NameWithOwner: "a/b", Stars: 99},
+		{ID: "2", Url: "https://github.com/c/d", NameWithOwner: "c/d", Stars: 250},
+		{ID: "5", Url: "https://github.com/e/f", NameWithOwner: "e/f", Stars: 150},
+		{ID: "6", Url: "https://github.com/g/h", NameWithOwner: "g/h", Stars: 50},
+	})
+
+	changes, err := changesSince(&Config{Filter: "stars >= 100", ChangelogMinDelta: 1}, prev, snap)
+	if err != nil {
+		t.Fatalf("changesSince() returned an error: %v", err)
+	}
+
+	names := func(list []Star) []string {
+		var n []string
+		for _, s := range list {
+			n = append(n, s.NameWithOwner)
+		}
+		return n
+	}
+	if got := names(changes.Added); !slices.Equal(got, []string{"e/f"}) {
+		t.Errorf("Added = %v, want [e/f]", got)
+	}
+	if got := names(changes.Removed); !slices.Equal(got, []string{"x/y"}) {
+		t.Errorf("Removed = %v, want [x/y], a repository no longer matching the filter isn't unstarred", got)
+	}
+	if len(changes.StarDeltas) != 1 || changes.StarDeltas[0].Star.NameWithOwner != "c/d" {
+		t.Errorf("Expected only the star delta of c/d, got %+v", changes.StarDeltas)
+	}
+
+	if changes, err := changesSince(&Config{}, nil, snap); err != nil || changes != nil {
+		t.Errorf("Expected no changes without a previous snapshot, got %v, %v", changes, err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	JSONFormat = "json"
+	YAMLFormat = "yaml"
+
+	// exportSchemaVersion is the version of the Export document. Increase it
+	// whenever a field is renamed or removed.
+	exportSchemaVersion = 1
+)
+
+// renderer writes the template model in an output format.
+type renderer interface {
+	render(w io.Writer, data T) error
+}
+
+// newRenderer returns the renderer for the configured output format.
+// Anything that isn't a built-in format is treated as a template, html files
+// are rendered with html/template.
+func newRenderer(config *Config) (renderer, error) {
+	r, err := newFormatRenderer(config)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := r.(templateRenderer); !ok && config.ListTemplateDir != "" {
+		return nil, fmt.Errorf("a list template directory only works with the list and table formats and custom markdown templates, not %s", config.OutputFormat)
+	}
+	return r, nil
+}
+
+// newFormatRenderer returns the renderer of the output format. Format names are
+// case-insensitive, paths of custom templates are used as they are.
+func newFormatRenderer(config *Config) (renderer, error) {
+	format := strings.ToLower(config.OutputFormat)
+	switch format {
+	case JSONFormat:
+		return jsonRenderer{}, nil
+	case YAMLFormat, "yml":
+		return yamlRenderer{}, nil
+	case CSVFormat:
+		return newCSVRenderer(',', config.Columns)
+	case TSVFormat:
+		return newCSVRenderer('\t', config.Columns)
+	case BookmarksFormat:
+		return bookmarksRenderer{}, nil
+	case OPMLFormat:
+		return opmlRenderer{}, nil
+	case SQLiteFormat:
+		return sqliteRenderer{}, nil
+	case AtomFormat, RSSFormat:
+		return newFeedRenderer(format, config)
+	case HTMLFormat:
+		return newHTMLRenderer(HTMLFormat)
+	case string(ListTemplate), string(TableTemplate):
+	default:
+		format = config.OutputFormat
+	}
+
+	if isHTMLTemplate(format) {
+		return newHTMLRenderer(format)
+	}
+
+	t, err := initTemplate(format, config.ListTemplateDir)
+	if err != nil {
+		return nil, err
+	}
+	// custom templates get the descriptions unescaped, they can use mdEscape
+	escape := escapeNone
+	switch TemplateType(format) {
+	case ListTemplate:
+		escape = escapeListItem
+	case TableTemplate:
+		escape = escapeTableCell
+	}
+	return templateRenderer{t: t, escape: escape}, nil
+}
+
+// Export is the document written by the json and yaml formats.
+type Export struct {
+	SchemaVersion int               `json:"schema_version" yaml:"schema_version"` // Version of the export format
+	User          string            `json:"user" yaml:"user"`                     // User whose stars are listed
+	FetchedAt     time.Time         `json:"fetched_at" yaml:"fetched_at"`         // When the stars were fetched
+	GroupBy       string            `json:"group_by" yaml:"group_by"`             // How the stars are grouped
+	Total         int               `json:"total" yaml:"total"`                   // Number of repositories
+	Keys          []string          `json:"keys" yaml:"keys"`                     // Group names, in order
+	Stars         map[string][]Star `json:"stars" yaml:"stars"`                   // Repositories per group
+}
+
+func newExport(data T) Export {
+	stars := data.Stars
+	if stars == nil {
+		stars = map[string][]Star{}
+	}
+	keys := data.Keys
+	if keys == nil {
+		keys = []string{}
+	}
+	return Export{
+		SchemaVersion: exportSchemaVersion,
+		User:          data.User,
+		FetchedAt:     data.FetchedAt,
+		GroupBy:       data.GroupBy,
+		Total:         data.Total,
+		Keys:          keys,
+		Stars:         stars,
+	}
+}
+
+// jsonRenderer writes the model as indented JSON.
+type jsonRenderer struct{}
+
+func (jsonRenderer) render(w io.Writer, data T) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newExport(data))
+}
+
+// yamlRenderer writes the model as YAML.
+type yamlRenderer struct{}
+
+func (yamlRenderer) render(w io.Writer, data T) error {
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	return enc.Encode(newExport(data))
+}
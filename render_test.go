@@ -0,0 +1,572 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// testT returns a template model with fixed data.
+func testT() T {
+	stars := map[string][]Star{
+		"Go": {
+			{
+				Url:           "https://github.com/user/repo1",
+				Name:          "repo1",
+				NameWithOwner: "user/repo1",
+				Description:   "Test repo 1",
+				Language:      "Go",
+				Topics:        []string{"cli"},
+				License:       "MIT",
+				Stars:         1234,
+				StarredAt:     time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		"Rust": {
+			{
+				Url:           "https://github.com/user/repo2",
+				Name:          "repo2",
+				NameWithOwner: "user/repo2",
+				Description:   "Test repo 2",
+				Language:      "Rust",
+				Stars:         5,
+				Archived:      true,
+				StarredAt:     time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	keys := []string{"Go", "Rust"}
+	return T{
+		User:        "testuser",
+		FetchedAt:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		GroupBy:     GroupByLanguage,
+		Total:       2,
+		Keys:        keys,
+		Anchors:     toc(keys),
+		Stars:       stars,
+		WithToc:     true,
+		WithLicense: true,
+		WithStars:   true,
+		Credits:     C{Text: creditText, Url: creditUrl, Link: "[stargazer](" + creditUrl + ")!"},
+	}
+}
+
+func TestExportRenderers(t *testing.T) {
+	data := testT()
+	expected := newExport(data)
+
+	tests := []struct {
+		format    string
+		unmarshal func([]byte, interface{}) error
+	}{
+		{JSONFormat, json.Unmarshal},
+		{YAMLFormat, yaml.Unmarshal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r, err := newRenderer(&Config{OutputFormat: tt.format})
+			if err != nil {
+				t.Fatalf("newRenderer() returned an error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := r.render(&buf, data); err != nil {
+				t.Fatalf("render() returned an error: %v", err)
+			}
+
+			var got Export
+			if err := tt.unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to parse output: %v\n%s", err, buf.String())
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("Export does not match. Got %+v, want %+v", got, expected)
+			}
+			for _, field := range []string{"schema_version", "fetched_at", "name_with_owner", "starred_at"} {
+				if !bytes.Contains(buf.Bytes(), []byte(field)) {
+					t.Errorf("Expected field %q in output:\n%s", field, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCSVRenderer(t *testing.T) {
+	data := testT()
+	data.Stars["Go"][0].Description = "Fast, \"simple\"\nand\tsmall"
+
+	tests := []struct {
+		format  string
+		comma   rune
+		columns []string
+		header  []string
+		first   []string
+	}{
+		{CSVFormat, ',', nil, defaultCSVColumns,
+			[]string{"Go", "repo1", "user", "https://github.com/user/repo1", "Fast, \"simple\"\nand\tsmall", "MIT", "1234", "false", "2024-05-01T10:00:00Z"}},
+		{TSVFormat, '\t', []string{"Stars", "name_with_owner", "description", "topics"}, []string{"stars", "name_with_owner", "description", "topics"},
+			[]string{"1234", "user/repo1", "Fast, \"simple\"\nand\tsmall", "cli"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r, err := newRenderer(&Config{OutputFormat: tt.format, Columns: tt.columns})
+			if err != nil {
+				t.Fatalf("newRenderer() returned an error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := r.render(&buf, data); err != nil {
+				t.Fatalf("render() returned an error: %v", err)
+			}
+
+			cr := csv.NewReader(&buf)
+			cr.Comma = tt.comma
+			rows, err := cr.ReadAll()
+			if err != nil {
+				t.Fatalf("Failed to parse output: %v", err)
+			}
+			if len(rows) != 3 {
+				t.Fatalf("Expected header and 2 rows, got %d", len(rows))
+			}
+			if !reflect.DeepEqual(rows[0], tt.header) {
+				t.Errorf("Header = %v, want %v", rows[0], tt.header)
+			}
+			if !reflect.DeepEqual(rows[1], tt.first) {
+				t.Errorf("First row = %q, want %q", rows[1], tt.first)
+			}
+		})
+	}
+
+	if _, err := newRenderer(&Config{OutputFormat: CSVFormat, Columns: []string{"forks"}}); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
+
+func TestBookmarksRenderer(t *testing.T) {
+	data := testT()
+	data.Stars["Go"][0].Description = "Fast & <small>\nlibrary"
+
+	var buf bytes.Buffer
+	if err := (bookmarksRenderer{}).render(&buf, data); err != nil {
+		t.Fatalf("render() returned an error: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"<!DOCTYPE NETSCAPE-Bookmark-file-1>\n",
+		`<DT><H3 ADD_DATE="1714608000">Starred repositories of testuser</H3>`,
+		`<DT><H3 ADD_DATE="1714608000">Go</H3>`,
+		`<DT><A HREF="https://github.com/user/repo1" ADD_DATE="1714557600">user/repo1</A>`,
+		"<DD>Fast &amp; &lt;small&gt; library\n",
+		`<DT><H3 ADD_DATE="1714608000">Rust</H3>`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected %q in output:\n%s", e, out)
+		}
+	}
+	if strings.Count(out, "<DL><p>") != strings.Count(out, "</DL><p>") {
+		t.Errorf("Unbalanced folders in output:\n%s", out)
+	}
+	if strings.Index(out, ">Go</H3>") > strings.Index(out, ">Rust</H3>") {
+		t.Errorf("Expected folders in key order:\n%s", out)
+	}
+}
+
+func TestHTMLRenderer(t *testing.T) {
+	data := testT()
+	data.Stars["Go"][0].Description = `<script>alert("x")</script>`
+
+	for _, format := range []string{HTMLFormat, "custom_template.html"} {
+		t.Run(format, func(t *testing.T) {
+			r, err := newRenderer(&Config{OutputFormat: format})
+			if err != nil {
+				t.Fatalf("newRenderer() returned an error: %v", err)
+			}
+			if _, ok := r.(htmlRenderer); !ok {
+				t.Fatalf("Expected an html renderer, got %T", r)
+			}
+
+			var buf bytes.Buffer
+			if err := r.render(&buf, data); err != nil {
+				t.Fatalf("render() returned an error: %v", err)
+			}
+			out := buf.String()
+
+			expected := []string{
+				`<a href="#go">Go</a>`,
+				`<a href="https://github.com/user/repo1">user/repo1</a>`,
+				"&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;",
+			}
+			for _, e := range expected {
+				if !strings.Contains(out, e) {
+					t.Errorf("Expected %q in output:\n%s", e, out)
+				}
+			}
+			if strings.Contains(out, "](") {
+				t.Errorf("Unexpected markdown link in output:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestFeedRenderer(t *testing.T) {
+	data := testT()
+	data.Stars["Old"] = []Star{{
+		Url:           "https://github.com/user/old",
+		NameWithOwner: "user/old",
+		StarredAt:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	data.Keys = append(data.Keys, "Old")
+	data.Total++
+
+	t.Run(AtomFormat, func(t *testing.T) {
+		r, err := newRenderer(&Config{OutputFormat: AtomFormat, FeedLimit: 2})
+		if err != nil {
+			t.Fatalf("newRenderer() returned an error: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := r.render(&buf, data); err != nil {
+			t.Fatalf("render() returned an error: %v", err)
+		}
+
+		var feed atomFeed
+		if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+			t.Fatalf("Failed to parse feed: %v\n%s", err, buf.String())
+		}
+		if feed.ID != "https://github.com/testuser?tab=stars" || feed.Updated != "2024-05-01T10:00:00Z" {
+			t.Errorf("Unexpected feed metadata %+v", feed)
+		}
+		if len(feed.Entries) != 2 || feed.Entries[0].Title != "user/repo1" || feed.Entries[1].Title != "user/repo2" {
+			t.Fatalf("Expected the 2 newest entries, got %+v", feed.Entries)
+		}
+		if len(feed.Entries[0].Categories) != 2 || feed.Entries[0].Categories[1].Term != "cli" {
+			t.Errorf("Expected language and topics as categories, got %+v", feed.Entries[0].Categories)
+		}
+	})
+
+	t.Run(RSSFormat, func(t *testing.T) {
+		r, err := newRenderer(&Config{OutputFormat: RSSFormat, FeedMaxAgeDays: 90})
+		if err != nil {
+			t.Fatalf("newRenderer() returned an error: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := r.render(&buf, data); err != nil {
+			t.Fatalf("render() returned an error: %v", err)
+		}
+
+		var feed rssFeed
+		if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+			t.Fatalf("Failed to parse feed: %v\n%s", err, buf.String())
+		}
+		items := feed.Channel.Items
+		if len(items) != 2 {
+			t.Fatalf("Expected entries of the last 90 days only, got %+v", items)
+		}
+		if items[0].GUID.Value != "https://github.com/user/repo1" || items[0].PubDate != "Wed, 01 May 2024 10:00:00 +0000" {
+			t.Errorf("Unexpected first item %+v", items[0])
+		}
+	})
+}
+
+func TestOPMLRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (opmlRenderer{}).render(&buf, testT()); err != nil {
+		t.Fatalf("render() returned an error: %v", err)
+	}
+
+	var doc opml
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse outline: %v\n%s", err, buf.String())
+	}
+
+	if doc.Version != "2.0" || doc.Head.Title != "Releases of starred repositories of testuser" {
+		t.Errorf("Unexpected head %+v", doc.Head)
+	}
+	if len(doc.Body) != 2 || doc.Body[0].Text != "Go" || doc.Body[1].Text != "Rust" {
+		t.Fatalf("Expected a folder per group, got %+v", doc.Body)
+	}
+	feed := doc.Body[0].Outlines[0]
+	if feed.Type != "rss" || feed.XMLUrl != "https://github.com/user/repo1/releases.atom" || feed.HTMLUrl != "https://github.com/user/repo1" {
+		t.Errorf("Unexpected feed outline %+v", feed)
+	}
+}
+
+func TestSQLiteRenderer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stars.db")
+	data := testT()
+	repo4 := Star{Url: "https://github.com/user/repo4", Name: "repo4", NameWithOwner: "user/repo4", Language: "Go"}
+	data.Stars["Go"] = append(data.Stars["Go"], repo4)
+	data.All = []Star{data.Stars["Go"][0], repo4, data.Stars["Rust"][0]}
+	data.Total = 3
+	if err := writeList(path, sqliteRenderer{}, data); err != nil {
+		t.Fatalf("writeList() returned an error: %v", err)
+	}
+
+	// Second fetch: repo4 was unstarred, repo1 gained stars, repo2 is still starred
+	// but filtered from the list and repo3 was starred but is filtered as well.
+	repo1 := data.Stars["Go"][0]
+	repo1.Stars = 1300
+	repo3 := Star{Url: "https://github.com/user/repo3", Name: "repo3", NameWithOwner: "user/repo3", Language: "Go"}
+	data.FetchedAt = data.FetchedAt.Add(24 * time.Hour)
+	data.All = []Star{repo1, data.Stars["Rust"][0], repo3}
+	data.Stars = map[string][]Star{"Go": {repo1}}
+	data.Keys = []string{"Go"}
+	data.Total = 1
+	if err := writeList(path, sqliteRenderer{}, data); err != nil {
+		t.Fatalf("writeList() returned an error on update: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"SELECT COUNT(*) FROM snapshots", 2},
+		{"SELECT total FROM snapshots WHERE fetched_at = '2024-05-03T00:00:00Z'", 1},
+		{"SELECT COUNT(*) FROM repositories", 3},
+		{"SELECT COUNT(*) FROM repositories WHERE name_with_owner = 'user/repo3'", 0},
+		{"SELECT COUNT(*) FROM repositories WHERE starred = 1", 2},
+		{"SELECT starred FROM repositories WHERE name_with_owner = 'user/repo2'", 1},
+		{"SELECT starred FROM repositories WHERE name_with_owner = 'user/repo4'", 0},
+		{"SELECT COUNT(*) FROM languages", 2},
+		{"SELECT COUNT(*) FROM topics WHERE repository = 'https://github.com/user/repo1' AND topic = 'cli'", 1},
+		{"SELECT stars FROM repositories WHERE name_with_owner = 'user/repo1'", 1300},
+		{"SELECT COUNT(*) FROM repository_history WHERE repository = 'https://github.com/user/repo1'", 2},
+		{"SELECT COUNT(*) FROM repositories WHERE first_seen = '2024-05-02T00:00:00Z' AND last_seen = '2024-05-03T00:00:00Z'", 1},
+		{"SELECT COUNT(*) FROM repository_history WHERE fetched_at = '2024-05-03T00:00:00Z'", 1},
+	}
+	for _, tt := range tests {
+		var got int
+		if err := db.QueryRow(tt.query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+
+	if err := (sqliteRenderer{}).render(&bytes.Buffer{}, data); err == nil {
+		t.Error("Expected an error rendering sqlite to a stream")
+	}
+}
+
+// failingRenderer writes part of the output and then fails.
+type failingRenderer struct{}
+
+func (failingRenderer) render(w io.Writer, data T) error {
+	io.WriteString(w, "partial")
+	return errors.New("template failed")
+}
+
+func TestWriteList(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "README.md")
+	if err := os.WriteFile(path, []byte("old list"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeList(path, failingRenderer{}, testT()); err == nil {
+		t.Fatal("Expected the render error to be returned")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "old list" {
+		t.Errorf("Expected the file to be kept on a render error, got %q", b)
+	}
+
+	if err := writeList(path, jsonRenderer{}, testT()); err != nil {
+		t.Fatalf("writeList() returned an error: %v", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the permissions to be kept, got %v", fi.Mode().Perm())
+	}
+
+	changed, err := writeFile(path, mustReadFile(t, path))
+	if err != nil {
+		t.Fatalf("writeFile() returned an error: %v", err)
+	}
+	if changed {
+		t.Error("Expected identical content not to be written")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %v", entries)
+	}
+}
+
+func TestTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"header.md":  "# Stars of {{ .User }}\n",
+		"entry.tmpl": "\n* {{ .NameWithOwner }}{{ if .List.WithStars }} {{ humanize .Stars }}{{ end }}",
+		".hidden":    "ignored",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		format   string
+		expected []string
+	}{
+		{"list", []string{"# Stars of testuser\n", "## Go\n\n* user/repo1 1.2k\n", "* user/repo2 5"}},
+		{"table", []string{"# Stars of testuser\n", "| Name  | Description", "|----: |\n* user/repo1 1.2k"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r, err := newRenderer(&Config{OutputFormat: tt.format, ListTemplateDir: dir})
+			if err != nil {
+				t.Fatalf("newRenderer() returned an error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := r.render(&buf, testT()); err != nil {
+				t.Fatalf("render() returned an error: %v", err)
+			}
+			out := buf.String()
+			for _, e := range tt.expected {
+				if !strings.Contains(out, e) {
+					t.Errorf("Expected %q in output:\n%s", e, out)
+				}
+			}
+			if strings.Contains(out, "Awesome Starred Repos") {
+				t.Errorf("Expected the header to be replaced:\n%s", out)
+			}
+		})
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.md"), []byte(`{{ range .Keys }}{{ template "group" (group $ .) }}{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := newRenderer(&Config{OutputFormat: "list", ListTemplateDir: dir})
+	if err != nil {
+		t.Fatalf("newRenderer() returned an error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := r.render(&buf, testT()); err != nil {
+		t.Fatalf("render() returned an error: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "Stars of") || !strings.HasPrefix(out, "\n## Go\n") {
+		t.Errorf("Expected the main template to be replaced:\n%s", out)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	custom := filepath.Join(t.TempDir(), "custom.md")
+	if err := os.WriteFile(custom, []byte("{{ range .Keys }}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unknown := t.TempDir()
+	if err := os.WriteFile(filepath.Join(unknown, "badge.md"), []byte("badge"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	twice := t.TempDir()
+	for _, name := range []string{"entry.md", "entry.tmpl"} {
+		if err := os.WriteFile(filepath.Join(twice, name), []byte("entry"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		config   *Config
+		expected string
+	}{
+		{"unknown format", &Config{OutputFormat: "markdown"}, `unknown format or template "markdown"`},
+		{"directory as format", &Config{OutputFormat: unknown}, "is a directory"},
+		{"parse error", &Config{OutputFormat: custom}, "cannot parse template " + custom},
+		{"missing directory", &Config{OutputFormat: "list", ListTemplateDir: filepath.Join(unknown, "missing")}, "cannot read template directory"},
+		{"empty directory", &Config{OutputFormat: "list", ListTemplateDir: t.TempDir()}, "no templates found"},
+		{"unknown partial", &Config{OutputFormat: "list", ListTemplateDir: unknown}, "unknown template " + filepath.Join(unknown, "badge.md")},
+		{"partial twice", &Config{OutputFormat: "list", ListTemplateDir: twice}, "both replace entry"},
+		{"non-template format", &Config{OutputFormat: JSONFormat, ListTemplateDir: twice}, "only works with the list and table formats"},
+		{"html format", &Config{OutputFormat: HTMLFormat, ListTemplateDir: twice}, "not html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRenderer(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestFormatCase(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected renderer
+		escape   escapeContext
+	}{
+		{"LIST", templateRenderer{}, escapeListItem},
+		{"Table", templateRenderer{}, escapeTableCell},
+		{"JSON", jsonRenderer{}, escapeRaw},
+		{"SQLite", sqliteRenderer{}, escapeRaw},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r, err := newFormatRenderer(&Config{OutputFormat: tt.format})
+			if err != nil {
+				t.Fatalf("newFormatRenderer() returned an error: %v", err)
+			}
+			if reflect.TypeOf(r) != reflect.TypeOf(tt.expected) || escapeContextOf(r) != tt.escape {
+				t.Errorf("Expected %T with escape context %v, got %T with %v", tt.expected, tt.escape, r, escapeContextOf(r))
+			}
+		})
+	}
+}
+
+func TestActionDefaults(t *testing.T) {
+	var action struct {
+		Inputs map[string]struct {
+			Default string `yaml:"default"`
+		} `yaml:"inputs"`
+	}
+	if err := yaml.Unmarshal(mustReadFile(t, "action.yml"), &action); err != nil {
+		t.Fatalf("Failed to parse action.yml: %v", err)
+	}
+
+	format := action.Inputs["format"].Default
+	r, err := newFormatRenderer(&Config{OutputFormat: format})
+	if err != nil {
+		t.Fatalf("Default format %q of the action is invalid: %v", format, err)
+	}
+	if tr, ok := r.(templateRenderer); !ok || tr.escape != escapeListItem {
+		t.Errorf("Expected the default format %q to be the list template, got %T", format, r)
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
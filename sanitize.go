@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	EmojiKeep    = "keep"
+	EmojiStrip   = "strip"
+	EmojiConvert = "convert"
+)
+
+var availableEmojiModes = []string{EmojiKeep, EmojiStrip, EmojiConvert}
+
+// escapeContext is where a renderer puts the descriptions.
+type escapeContext int
+
+const (
+	escapeRaw       escapeContext = iota // data formats, the descriptions are kept as they are
+	escapeNone                           // custom templates, which decide about escaping
+	escapeListItem                       // markdown list item
+	escapeTableCell                      // markdown table cell
+	escapeHTML                           // html, escaped by html/template
+)
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+	shortcode  = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+
+	// markdownEscapes are the characters with a meaning in markdown text and their
+	// escapes, shared by the sanitizer and mdEscape.
+	markdownEscapes = []string{
+		`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+	}
+	// tableCellEscapes additionally escape the separator of table cells.
+	tableCellEscapes = slices.Concat(markdownEscapes, []string{"|", `\|`})
+
+	listItemReplacer  = strings.NewReplacer(markdownEscapes...)
+	tableCellReplacer = strings.NewReplacer(tableCellEscapes...)
+)
+
+// escapeContextOf returns where the renderer puts the descriptions.
+func escapeContextOf(r renderer) escapeContext {
+	switch r := r.(type) {
+	case templateRenderer:
+		return r.escape
+	case htmlRenderer, pageRenderer:
+		return escapeHTML
+	}
+	return escapeRaw
+}
+
+// validateEmojiMode checks the emoji mode of the descriptions.
+func validateEmojiMode(mode string) error {
+	switch mode {
+	case "", EmojiKeep, EmojiStrip, EmojiConvert:
+		return nil
+	}
+	return fmt.Errorf("unknown emoji mode %q, available: %s", mode, strings.Join(availableEmojiModes, ", "))
+}
+
+// sanitizer prepares descriptions for where a renderer puts them: on a single
+// line, with shortcode emoji handled as configured, shortened to the maximum
+// length and escaped for markdown. It runs after grouping, so categories and
+// filters match the original descriptions. Data formats like json and csv get
+// the descriptions as they are.
+type sanitizer struct {
+	ctx       escapeContext
+	emojiMode string
+	maxLength int
+}
+
+// newSanitizer returns a sanitizer for the configuration and escape context.
+func newSanitizer(config *Config, ctx escapeContext) (sanitizer, error) {
+	if err := validateEmojiMode(config.DescriptionEmoji); err != nil {
+		return sanitizer{}, err
+	}
+	return sanitizer{ctx: ctx, emojiMode: config.DescriptionEmoji, maxLength: config.DescriptionMaxLength}, nil
+}
+
+// stars returns a copy of the stars with sanitized descriptions.
+func (s sanitizer) stars(list []Star) []Star {
+	sanitized := make([]Star, len(list))
+	for i, star := range list {
+		star.Description = sanitizeDescription(star.Description, s.ctx, s.emojiMode, s.maxLength)
+		sanitized[i] = star
+	}
+	return sanitized
+}
+
+// groups returns a copy of the grouped stars with sanitized descriptions.
+func (s sanitizer) groups(stars map[string][]Star) map[string][]Star {
+	sanitized := make(map[string][]Star, len(stars))
+	for k, list := range stars {
+		sanitized[k] = s.stars(list)
+	}
+	return sanitized
+}
+
+// changes returns a copy of the changes with sanitized descriptions, nil without changes.
+func (s sanitizer) changes(c *Changelog) *Changelog {
+	if c == nil {
+		return nil
+	}
+	sanitized := *c
+	sanitized.Added = s.stars(c.Added)
+	sanitized.Removed = s.stars(c.Removed)
+	sanitized.Archived = s.stars(c.Archived)
+	sanitized.Renamed = make([]Rename, len(c.Renamed))
+	for i, r := range c.Renamed {
+		r.Star.Description = sanitizeDescription(r.Star.Description, s.ctx, s.emojiMode, s.maxLength)
+		sanitized.Renamed[i] = r
+	}
+	sanitized.StarDeltas = make([]StarDelta, len(c.StarDeltas))
+	for i, d := range c.StarDeltas {
+		d.Star.Description = sanitizeDescription(d.Star.Description, s.ctx, s.emojiMode, s.maxLength)
+		sanitized.StarDeltas[i] = d
+	}
+	return &sanitized
+}
+
+// sanitizeDescription prepares a description for the context.
+func sanitizeDescription(d string, ctx escapeContext, emojiMode string, maxLength int) string {
+	if ctx == escapeRaw {
+		return d
+	}
+	switch emojiMode {
+	case EmojiStrip:
+		d = replaceShortcodes(d, func(string) (string, bool) { return "", true })
+	case EmojiConvert:
+		d = replaceShortcodes(d, func(name string) (string, bool) {
+			e, ok := shortcodeEmoji[name]
+			return e, ok
+		})
+	}
+
+	d = strings.TrimSpace(whitespace.ReplaceAllString(d, " "))
+	d = truncate(maxLength, d)
+
+	switch ctx {
+	case escapeListItem:
+		d = listItemReplacer.Replace(d)
+	case escapeTableCell:
+		d = tableCellReplacer.Replace(d)
+	default:
+		return d
+	}
+	// keep a leading # from turning into a heading in templates that start a line with it
+	if strings.HasPrefix(d, "#") {
+		d = `\` + d
+	}
+	return d
+}
+
+// replaceShortcodes replaces the shortcodes like :rocket: in s for which repl
+// returns true. Colons within words, like in 10:30:00, aren't shortcodes.
+func replaceShortcodes(s string, repl func(name string) (string, bool)) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(s); {
+		loc := shortcode.FindStringSubmatchIndex(s[i:])
+		if loc == nil {
+			break
+		}
+		start, end := i+loc[0], i+loc[1]
+		if start > 0 && isWordChar(s[start-1]) {
+			i = start + 1
+			continue
+		}
+		r, ok := repl(s[i+loc[2] : i+loc[3]])
+		if !ok {
+			i = start + 1
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(r)
+		last, i = end, end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// shortcodeEmoji are the GitHub shortcodes converted to emoji, the more common
+// ones in repository descriptions.
+var shortcodeEmoji = map[string]string{
+	"+1":                       "👍",
+	"art":                      "🎨",
+	"bar_chart":                "📊",
+	"books":                    "📚",
+	"bookmark":                 "🔖",
+	"boom":                     "💥",
+	"bug":                      "🐛",
+	"bulb":                     "💡",
+	"calendar":                 "📆",
+	"camera":                   "📷",
+	"chart_with_upwards_trend": "📈",
+	"check":                    "✔️",
+	"clipboard":                "📋",
+	"cloud":                    "☁️",
+	"coffee":                   "☕",
+	"computer":                 "💻",
+	"construction":             "🚧",
+	"crab":                     "🦀",
+	"crystal_ball":             "🔮",
+	"dart":                     "🎯",
+	"desktop_computer":         "🖥️",
+	"dog":                      "🐶",
+	"earth_americas":           "🌎",
+	"electric_plug":            "🔌",
+	"eyes":                     "👀",
+	"fire":                     "🔥",
+	"floppy_disk":              "💾",
+	"gear":                     "⚙️",
+	"gem":                      "💎",
+	"globe_with_meridians":     "🌐",
+	"hammer":                   "🔨",
+	"hammer_and_wrench":        "🛠️",
+	"heart":                    "❤️",
+	"heavy_check_mark":         "✔️",
+	"hourglass":                "⌛",
+	"iphone":                   "📱",
+	"key":                      "🔑",
+	"lock":                     "🔒",
+	"mag":                      "🔍",
+	"memo":                     "📝",
+	"moon":                     "🌔",
+	"musical_note":             "🎵",
+	"package":                  "📦",
+	"paintbrush":               "🖌️",
+	"pencil":                   "📝",
+	"pencil2":                  "✏️",
+	"penguin":                  "🐧",
+	"rainbow":                  "🌈",
+	"robot":                    "🤖",
+	"rocket":                   "🚀",
+	"rotating_light":           "🚨",
+	"shield":                   "🛡️",
+	"snake":                    "🐍",
+	"sparkles":                 "✨",
+	"star":                     "⭐",
+	"star2":                    "🌟",
+	"tada":                     "🎉",
+	"telescope":                "🔭",
+	"test_tube":                "🧪",
+	"thumbsup":                 "👍",
+	"tools":                    "🛠️",
+	"trophy":                   "🏆",
+	"unicorn":                  "🦄",
+	"warning":                  "⚠️",
+	"whale":                    "🐳",
+	"white_check_mark":         "✅",
+	"wrench":                   "🔧",
+	"zap":                      "⚡",
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSanitizeDescription(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		ctx       escapeContext
+		emojiMode string
+		maxLength int
+		expected  string
+	}{
+		{"plain", "A plain description", escapeListItem, EmojiKeep, 0, "A plain description"},
+		{"whitespace", "  multi\nline\r\n\tdescription ", escapeNone, EmojiKeep, 0, "multi line description"},
+		{"list item", "a *b* _c_ [d](e) <f> `g` a|b", escapeListItem, EmojiKeep, 0, "a \\*b\\* \\_c\\_ \\[d\\](e) \\<f\\> \\`g\\` a|b"},
+		{"table cell", "a | b\nc *d*", escapeTableCell, EmojiKeep, 0, "a \\| b c \\*d\\*"},
+		{"backslash", `C:\path`, escapeTableCell, EmojiKeep, 0, `C:\\path`},
+		{"leading hash", "# Not a heading", escapeListItem, EmojiKeep, 0, `\# Not a heading`},
+		{"html", "a <b> & *c* | d", escapeHTML, EmojiKeep, 0, "a <b> & *c* | d"},
+		{"keep emoji", ":rocket: Fast", escapeNone, EmojiKeep, 0, ":rocket: Fast"},
+		{"strip emoji", ":rocket::fire: Fast :sparkles: tool", escapeNone, EmojiStrip, 0, "Fast tool"},
+		{"convert emoji", ":rocket::fire: Fast :unknown_code: tool", escapeNone, EmojiConvert, 0, "🚀🔥 Fast :unknown_code: tool"},
+		{"not a shortcode", "Runs at 10:30:00 on a:b:c", escapeNone, EmojiStrip, 0, "Runs at 10:30:00 on a:b:c"},
+		{"max length", "A description that is too long", escapeNone, EmojiKeep, 14, "A description…"},
+		{"max length before escaping", "a_b_c_d_e", escapeListItem, EmojiKeep, 5, "a\\_b\\_…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeDescription(tt.in, tt.ctx, tt.emojiMode, tt.maxLength); got != tt.expected {
+				t.Errorf("sanitizeDescription(%q) = %q, want %q", tt.in, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSanitizer(t *testing.T) {
+	list := []Star{{NameWithOwner: "user/repo", Description: "a | b\n:rocket:"}}
+
+	r, err := newRenderer(&Config{OutputFormat: "table"})
+	if err != nil {
+		t.Fatalf("newRenderer() returned an error: %v", err)
+	}
+	san, err := newSanitizer(&Config{DescriptionEmoji: EmojiConvert}, escapeContextOf(r))
+	if err != nil {
+		t.Fatalf("newSanitizer() returned an error: %v", err)
+	}
+	sanitized := san.groups(map[string][]Star{"Go": list})
+	if d := sanitized["Go"][0].Description; d != `a \| b 🚀` {
+		t.Errorf("Unexpected description %q", d)
+	}
+	if list[0].Description != "a | b\n:rocket:" {
+		t.Error("Expected the stars not to be modified")
+	}
+
+	data := testT()
+	data.Stars = sanitized
+	data.Keys = []string{"Go"}
+	var buf bytes.Buffer
+	if err := r.render(&buf, data); err != nil {
+		t.Fatalf("render() returned an error: %v", err)
+	}
+	out := buf.String()
+	header, row := lineWith(t, out, "| Name"), lineWith(t, out, "user/repo")
+	if cells(row) != cells(header) {
+		t.Errorf("Expected the row to keep the columns of the header:\n%s\n%s", header, row)
+	}
+
+	if _, err := newSanitizer(&Config{DescriptionEmoji: "remove"}, escapeContextOf(r)); err == nil {
+		t.Error("Expected an error for an unknown emoji mode")
+	}
+}
+
+func TestSanitizeAfterGrouping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "README.md")
+	config := &Config{
+		OutputFile:           path,
+		OutputFormat:         "list",
+		GroupBy:              GroupByCategory,
+		Categories:           []Category{{Name: "Operators", Rules: []Rule{{Description: "kubernetes_operator"}, {Description: "at the end"}}}},
+		DescriptionMaxLength: 24,
+	}
+	snap := NewSnapshot("testuser", []Star{
+		{Url: "https://github.com/user/op", NameWithOwner: "user/op", Description: "A kubernetes_operator"},
+		{Url: "https://github.com/user/long", NameWithOwner: "user/long", Description: "A long description with the match at the end"},
+	})
+
+	r, err := newRenderer(config)
+	if err != nil {
+		t.Fatalf("newRenderer() returned an error: %v", err)
+	}
+	if _, err := renderOutput(config, r, snap, nil); err != nil {
+		t.Fatalf("renderOutput() returned an error: %v", err)
+	}
+
+	out := string(mustReadFile(t, path))
+	for _, e := range []string{"## Operators", `A kubernetes\_operator`, "A long description with…"} {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected %q in output:\n%s", e, out)
+		}
+	}
+	if strings.Contains(out, "## "+defaultFallbackCategory) {
+		t.Errorf("Expected the categories to match the original descriptions:\n%s", out)
+	}
+}
+
+func TestSanitizeChanges(t *testing.T) {
+	changes := &Changelog{
+		Until:      time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		Added:      []Star{{NameWithOwner: "user/repo1", Url: "https://github.com/user/repo1", Description: "Uses <b>\n*bold* [links]"}},
+		StarDeltas: []StarDelta{{Star: Star{NameWithOwner: "user/repo2", Description: "a_b"}, Previous: 1, Delta: 20}},
+	}
+	san, err := newSanitizer(&Config{}, escapeListItem)
+	if err != nil {
+		t.Fatalf("newSanitizer() returned an error: %v", err)
+	}
+	if san.changes(nil) != nil {
+		t.Error("Expected no changes without changes")
+	}
+
+	sanitized := san.changes(changes)
+	if d := sanitized.StarDeltas[0].Star.Description; d != `a\_b` {
+		t.Errorf("Unexpected description %q", d)
+	}
+	if changes.Added[0].Description != "Uses <b>\n*bold* [links]" {
+		t.Error("Expected the changes not to be modified")
+	}
+
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := writeChangelog(path, sanitized); err != nil {
+		t.Fatalf("writeChangelog() returned an error: %v", err)
+	}
+	expected := "  - [user/repo1](https://github.com/user/repo1) - Uses \\<b\\> \\*bold\\* \\[links\\]\n"
+	if out := string(mustReadFile(t, path)); !strings.Contains(out, expected) {
+		t.Errorf("Expected %q in changelog:\n%s", expected, out)
+	}
+}
+
+// lineWith returns the first line of out containing s.
+func lineWith(t *testing.T, out, s string) string {
+	t.Helper()
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, s) {
+			return line
+		}
+	}
+	t.Fatalf("No line with %q in output:\n%s", s, out)
+	return ""
+}
+
+// cells returns the number of unescaped cell separators of a table row.
+func cells(row string) int {
+	return strings.Count(row, "|") - strings.Count(row, `\|`)
+}
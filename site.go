@@ -0,0 +1,164 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"path/filepath"
+)
+
+const (
+	defaultSiteDir     = "_site"
+	defaultRecentLimit = 50
+
+	recentTitle = "Recently starred"
+	ownersTitle = "Owners"
+)
+
+//go:embed site_templates/*.html
+var siteTemplates embed.FS
+
+// sitePageTemplates are the templates of the site, a template directory can
+// replace any of them. layout.html defines the header and footer of the pages.
+var sitePageTemplates = []string{"layout.html", "index.html", "group.html", "owners.html", "owner.html", "recent.html"}
+
+// sitePage is a page of the site, rendered with the named template.
+type sitePage struct {
+	file     string
+	template string
+	data     T
+}
+
+// pageRenderer renders one template of a template set.
+type pageRenderer struct {
+	t    *htmltemplate.Template
+	name string
+}
+
+func (r pageRenderer) render(w io.Writer, data T) error {
+	return r.t.ExecuteTemplate(w, r.name, data)
+}
+
+// initSiteTemplates parses the templates of the site. The style, script and groups
+// blocks of the html format are available to all pages. Templates in dir replace
+// the built-in templates of the same name.
+func initSiteTemplates(dir string) (*htmltemplate.Template, error) {
+	t, err := htmltemplate.New(HTMLFormat).Funcs(templateFuncs).Parse(htmlPage)
+	if err != nil {
+		return nil, err
+	}
+	if t, err = t.ParseFS(siteTemplates, "site_templates/*.html"); err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return t, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no templates found in %s", dir)
+	}
+	return t.ParseFiles(files...)
+}
+
+// sitePages returns the pages of the site: an index of the groups, a page per
+// group and per owner, a list of the owners and the recently starred repositories.
+func sitePages(config *Config, data T, list []Star) ([]sitePage, error) {
+	pages := []sitePage{{file: "index.html", template: "index.html", data: data}}
+	for _, k := range data.Keys {
+		pages = append(pages, sitePage{
+			file:     "group-" + data.Anchors[k] + ".html",
+			template: "group.html",
+			data:     data.page(k, data.Stars[k]),
+		})
+	}
+
+	owners := groupStars(list, func(s Star) []string { return []string{s.Owner()} })
+	if err := sortStars(owners, config.Sort); err != nil {
+		return nil, err
+	}
+	ownerKeys, err := sortKeys(owners, GroupSortByName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	index := data.page(ownersTitle, nil)
+	index.GroupBy = GroupByOwner
+	index.Keys = ownerKeys
+	index.Anchors = toc(ownerKeys)
+	index.Stars = owners
+	index.Total = len(list)
+	pages = append(pages, sitePage{file: "owners.html", template: "owners.html", data: index})
+	for _, k := range ownerKeys {
+		p := data.page(k, owners[k])
+		p.GroupBy = GroupByOwner
+		pages = append(pages, sitePage{file: "owner-" + index.Anchors[k] + ".html", template: "owner.html", data: p})
+	}
+
+	recent := map[string][]Star{recentTitle: append([]Star(nil), list...)}
+	if err := sortStars(recent, SortByStarredAt+":desc"); err != nil {
+		return nil, err
+	}
+	if config.RecentLimit > 0 && len(recent[recentTitle]) > config.RecentLimit {
+		recent[recentTitle] = recent[recentTitle][:config.RecentLimit]
+	}
+	pages = append(pages, sitePage{file: "recent.html", template: "recent.html", data: data.page(recentTitle, recent[recentTitle])})
+
+	return pages, nil
+}
+
+// page returns the model of a page listing the stars under a single key.
+func (data T) page(key string, stars []Star) T {
+	p := data
+	p.Title = key
+	p.Keys = []string{key}
+	p.Anchors = toc(p.Keys)
+	p.Stars = map[string][]Star{key: stars}
+	p.Total = len(stars)
+	p.Changes = nil
+	return p
+}
+
+// writeSite renders the pages to dir and removes the pages of groups and owners
+// that no longer exist.
+func writeSite(dir string, t *htmltemplate.Template, pages []sitePage) error {
+	for _, p := range pages {
+		if t.Lookup(p.template) == nil {
+			return fmt.Errorf("template not found: %s", p.template)
+		}
+	}
+
+	if err := makeDir(dir); err != nil {
+		return err
+	}
+
+	written := make(map[string]bool, len(pages))
+	for _, p := range pages {
+		path := filepath.Join(dir, p.file)
+		if err := writeList(path, pageRenderer{t: t, name: p.template}, p.data); err != nil {
+			return fmt.Errorf("error writing %s: %v", path, err)
+		}
+		written[path] = true
+	}
+
+	for _, pattern := range []string{"group-*.html", "owner-*.html"} {
+		stale, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		for _, f := range stale {
+			if written[f] {
+				continue
+			}
+			if err := removeFile(f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
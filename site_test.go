@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSitePages(t *testing.T) {
+	data := testT()
+	list := append(append([]Star(nil), data.Stars["Go"]...), data.Stars["Rust"]...)
+	list = append(list, Star{Url: "https://github.com/other/repo3", NameWithOwner: "other/repo3", Language: "Go"})
+
+	pages, err := sitePages(&Config{RecentLimit: 2}, data, list)
+	if err != nil {
+		t.Fatalf("sitePages() returned an error: %v", err)
+	}
+
+	got := make(map[string]sitePage, len(pages))
+	for _, p := range pages {
+		got[p.file] = p
+	}
+
+	tests := []struct {
+		file     string
+		template string
+		keys     []string
+		total    int
+	}{
+		{"index.html", "index.html", []string{"Go", "Rust"}, 2},
+		{"group-go.html", "group.html", []string{"Go"}, 1},
+		{"group-rust.html", "group.html", []string{"Rust"}, 1},
+		{"owners.html", "owners.html", []string{"other", "user"}, 3},
+		{"owner-user.html", "owner.html", []string{"user"}, 2},
+		{"owner-other.html", "owner.html", []string{"other"}, 1},
+		{"recent.html", "recent.html", []string{recentTitle}, 2},
+	}
+	if len(pages) != len(tests) {
+		t.Errorf("Expected %d pages, got %d", len(tests), len(pages))
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			p, ok := got[tt.file]
+			if !ok {
+				t.Fatalf("Page %s not found", tt.file)
+			}
+			if p.template != tt.template {
+				t.Errorf("Expected template %s, got %s", tt.template, p.template)
+			}
+			if strings.Join(p.data.Keys, ",") != strings.Join(tt.keys, ",") {
+				t.Errorf("Expected keys %v, got %v", tt.keys, p.data.Keys)
+			}
+			if p.data.Total != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, p.data.Total)
+			}
+		})
+	}
+
+	recent := got["recent.html"].data.Stars[recentTitle]
+	if recent[0].NameWithOwner != "user/repo1" {
+		t.Errorf("Expected the most recently starred repository first, got %s", recent[0].NameWithOwner)
+	}
+}
+
+func TestWriteSite(t *testing.T) {
+	dir := t.TempDir()
+	templates := t.TempDir()
+	if err := os.WriteFile(filepath.Join(templates, "recent.html"), []byte(`custom {{ .Title }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "group-gone.html"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := initSiteTemplates(templates)
+	if err != nil {
+		t.Fatalf("initSiteTemplates() returned an error: %v", err)
+	}
+	data := testT()
+	pages, err := sitePages(&Config{}, data, data.Stars["Go"])
+	if err != nil {
+		t.Fatalf("sitePages() returned an error: %v", err)
+	}
+	if err := writeSite(dir, tmpl, pages); err != nil {
+		t.Fatalf("writeSite() returned an error: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "recent.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "custom "+recentTitle {
+		t.Errorf("Expected the custom template to be used, got %q", b)
+	}
+
+	b, err = os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `<a href="group-go.html">Go</a>`) {
+		t.Errorf("Expected a link to the group page in the index:\n%s", b)
+	}
+
+	if exists(filepath.Join(dir, "group-gone.html")) {
+		t.Error("Expected the stale group page to be removed")
+	}
+
+	if _, err := initSiteTemplates(t.TempDir()); err == nil {
+		t.Error("Expected an error for a template directory without templates")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// snapshotSchemaVersion is the version of the snapshot file format.
+// Increase it whenever a change to Snapshot or Star breaks older files.
+const snapshotSchemaVersion = 1
+
+// Snapshot is a persisted result of fetching the stars of a user.
+type Snapshot struct {
+	SchemaVersion int       `json:"schema_version"` // Version of the snapshot format
+	User          string    `json:"user"`           // User whose stars were fetched
+	FetchedAt     time.Time `json:"fetched_at"`     // When the stars were fetched
+	FullFetchAt   time.Time `json:"full_fetch_at"`  // When all stars were last fetched, not only the new ones
+	Stars         []Star    `json:"stars"`          // The fetched stars, unfiltered
+}
+
+// NewSnapshot creates a snapshot of the given stars, fetched just now.
+func NewSnapshot(user string, stars []Star) *Snapshot {
+	now := time.Now().UTC()
+	return &Snapshot{
+		SchemaVersion: snapshotSchemaVersion,
+		User:          user,
+		FetchedAt:     now,
+		FullFetchAt:   now,
+		Stars:         stars,
+	}
+}
+
+// LoadSnapshot reads a snapshot from a JSON file.
+// It returns an error if the file can't be read or was written by a newer version.
+func LoadSnapshot(filename string) (*Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %v", err)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error parsing snapshot: %v", err)
+	}
+	if s.SchemaVersion > snapshotSchemaVersion {
+		return nil, fmt.Errorf("snapshot %s has schema version %d, this version of %s supports up to %d",
+			filename, s.SchemaVersion, appName, snapshotSchemaVersion)
+	}
+
+	return &s, nil
+}
+
+// Save writes the snapshot to a JSON file.
+func (s *Snapshot) Save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling snapshot: %v", err)
+	}
+
+	if err := makeDir(filepath.Dir(filename)); err != nil {
+		return fmt.Errorf("error creating snapshot directory: %v", err)
+	}
+	if _, err := writeFile(filename, data); err != nil {
+		return fmt.Errorf("error writing snapshot: %v", err)
+	}
+
+	return nil
+}
+
+// loadStars returns the stars to render. With FromSnapshot they are read from the
+// snapshot file, otherwise they are fetched from the configured source and the
+// snapshot is refreshed. The previous snapshot is returned as well, if there is one.
+func loadStars(config *Config) (snap *Snapshot, prev *Snapshot, err error) {
+	if config.FromSnapshot {
+		if config.SnapshotFile == "" {
+			return nil, nil, fmt.Errorf("no snapshot file given")
+		}
+		snap, err := LoadSnapshot(config.SnapshotFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.WithField("fetched_at", snap.FetchedAt).Info("Using stars from snapshot ", config.SnapshotFile)
+		return snap, nil, nil
+	}
+
+	return refreshSnapshot(config)
+}
+
+// refreshSnapshot fetches the stars from the configured source and saves them to the
+// snapshot file. Test data is never saved. The replaced snapshot is returned as well,
+// if there was one.
+//
+// In incremental mode only the stars newer than the newest star of the previous
+// snapshot are fetched and merged into it. Unstarred repositories and changed
+// metadata are only picked up by a full fetch, which happens every FullRefreshDays.
+func refreshSnapshot(config *Config) (snap *Snapshot, prev *Snapshot, err error) {
+	prev = previousSnapshot(config)
+	since := incrementalSince(config, prev)
+
+	stars, err := fetchStars(config, since)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snap = NewSnapshot(config.GithubUser, stars)
+	if !since.IsZero() {
+		snap.Stars = mergeStars(stars, prev.Stars)
+		snap.FullFetchAt = prev.FullFetchAt
+		logger.WithFields(logrus.Fields{
+			"since": since,
+			"new":   len(stars),
+			"total": len(snap.Stars),
+		}).Info("Incrementally updated stars")
+	}
+
+	if config.SnapshotFile == "" || sourceName(config) == TestSource || dryRun {
+		return snap, prev, nil
+	}
+
+	if err := snap.Save(config.SnapshotFile); err != nil {
+		return nil, nil, err
+	}
+	logger.WithField("stars", len(snap.Stars)).Debug("Saved snapshot to ", config.SnapshotFile)
+
+	return snap, prev, nil
+}
+
+// previousSnapshot returns the snapshot saved by the last run for the same user, if any.
+func previousSnapshot(config *Config) *Snapshot {
+	if config.SnapshotFile == "" || sourceName(config) == TestSource || !exists(config.SnapshotFile) {
+		return nil
+	}
+
+	prev, err := LoadSnapshot(config.SnapshotFile)
+	if err != nil {
+		logger.WithError(err).Warn("Cannot use previous snapshot")
+		return nil
+	}
+	if prev.User != config.GithubUser {
+		return nil
+	}
+	return prev
+}
+
+// incrementalSince returns the time from which on stars need to be fetched to update
+// prev, or the zero time if all stars have to be fetched.
+func incrementalSince(config *Config, prev *Snapshot) time.Time {
+	if !config.Incremental || prev == nil || len(prev.Stars) == 0 {
+		return time.Time{}
+	}
+	if config.FullRefreshDays > 0 && time.Since(prev.FullFetchAt) >= time.Duration(config.FullRefreshDays)*24*time.Hour {
+		logger.WithField("full_fetch_at", prev.FullFetchAt).Info("Full refresh due, fetching all stars")
+		return time.Time{}
+	}
+	return newestStar(prev.Stars)
+}
+
+// newestStar returns the time the most recent of the given stars was starred.
+func newestStar(stars []Star) time.Time {
+	var newest time.Time
+	for _, s := range stars {
+		if s.StarredAt.After(newest) {
+			newest = s.StarredAt
+		}
+	}
+	return newest
+}
+
+// starKey identifies a repository across fetches. The node ID survives renames,
+// older snapshots don't have it, so the URL is used as fallback.
+func starKey(s Star) string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return strings.ToLower(s.Url)
+}
+
+// mergeStars adds the cached stars that are not part of the fresh ones.
+// Fresh stars come first and win over cached entries of the same repository.
+func mergeStars(fresh, cached []Star) []Star {
+	merged := make([]Star, 0, len(fresh)+len(cached))
+	seen := make(map[string]bool, len(fresh))
+	for _, s := range fresh {
+		seen[starKey(s)] = true
+		seen[strings.ToLower(s.Url)] = true
+		merged = append(merged, s)
+	}
+	for _, s := range cached {
+		if seen[starKey(s)] || seen[strings.ToLower(s.Url)] {
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".stargazer", "snapshot.json")
+	stars := []Star{
+		{
+			Url:           "https://github.com/user/repo1",
+			Name:          "repo1",
+			NameWithOwner: "user/repo1",
+			Language:      "Go",
+			Stars:         10,
+			StarredAt:     time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	snap := NewSnapshot("testuser", stars)
+	if err := snap.Save(path); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	loaded, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+
+	if loaded.SchemaVersion != snapshotSchemaVersion || loaded.User != "testuser" {
+		t.Errorf("Unexpected snapshot metadata %+v", loaded)
+	}
+	if !loaded.FetchedAt.Equal(snap.FetchedAt) {
+		t.Errorf("Expected fetch time %v, got %v", snap.FetchedAt, loaded.FetchedAt)
+	}
+	if !reflect.DeepEqual(loaded.Stars, stars) {
+		t.Errorf("Loaded stars do not match saved stars. Got %+v, want %+v", loaded.Stars, stars)
+	}
+}
+
+func TestLoadSnapshotNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, []byte(`{"schema_version": 999, "stars": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	if _, err := LoadSnapshot(path); err == nil {
+		t.Error("Expected an error for a snapshot with a newer schema version")
+	}
+}
+
+func TestLoadStarsFromSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, "stars.json")
+	snapshot := filepath.Join(dir, "snapshot.json")
+	data := `[{"url":"https://github.com/user/repo1","name":"repo1","name_with_owner":"user/repo1","language":"Go","stars":3}]`
+	if err := os.WriteFile(fixture, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	// fetching refreshes the snapshot
+	fetched, _, err := loadStars(&Config{Source: FileSource, SourceFile: fixture, SnapshotFile: snapshot})
+	if err != nil {
+		t.Fatalf("loadStars() returned an error: %v", err)
+	}
+	if !exists(snapshot) {
+		t.Fatal("Expected snapshot to be saved")
+	}
+
+	// rendering from the snapshot needs neither the source nor a token
+	if err := os.Remove(fixture); err != nil {
+		t.Fatalf("Failed to remove fixture: %v", err)
+	}
+	offline, _, err := loadStars(&Config{Source: GithubSource, SnapshotFile: snapshot, FromSnapshot: true})
+	if err != nil {
+		t.Fatalf("loadStars() returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(offline.Stars, fetched.Stars) {
+		t.Errorf("Expected stars from snapshot %+v, got %+v", fetched.Stars, offline.Stars)
+	}
+}
+
+func TestLoadStarsTestDataNotSaved(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "snapshot.json")
+	if _, _, err := loadStars(&Config{Test: true, SnapshotFile: snapshot}); err != nil {
+		t.Fatalf("loadStars() returned an error: %v", err)
+	}
+	if exists(snapshot) {
+		t.Error("Expected test data not to be saved as snapshot")
+	}
+}
+
+// recordingSource returns fixed stars and remembers the options it was called with.
+type recordingSource struct {
+	stars []Star
+	opts  *FetchOptions
+}
+
+func (r recordingSource) FetchStars(_ context.Context, opts FetchOptions) ([]Star, error) {
+	*r.opts = opts
+	return r.stars, nil
+}
+
+func TestRefreshSnapshotIncremental(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 10, 0, 0, 0, time.UTC) }
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	prev := NewSnapshot("testuser", []Star{
+		{ID: "R2", Url: "https://github.com/user/old-name", NameWithOwner: "user/old-name", Stars: 1, StarredAt: day(2)},
+		{ID: "R1", Url: "https://github.com/user/repo1", NameWithOwner: "user/repo1", Stars: 1, StarredAt: day(1)},
+	})
+	prev.FullFetchAt = time.Now().Add(-24 * time.Hour)
+	if err := prev.Save(path); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	var opts FetchOptions
+	RegisterStarSource("recording", recordingSource{
+		stars: []Star{
+			{ID: "R3", Url: "https://github.com/user/repo3", NameWithOwner: "user/repo3", Stars: 1, StarredAt: day(3)},
+			{ID: "R2", Url: "https://github.com/user/new-name", NameWithOwner: "user/new-name", Stars: 5, StarredAt: day(2)},
+		},
+		opts: &opts,
+	})
+	defer delete(starSources, "recording")
+
+	config := &Config{
+		GithubUser:      "testuser",
+		Source:          "recording",
+		SnapshotFile:    path,
+		Incremental:     true,
+		FullRefreshDays: 7,
+	}
+
+	snap, _, err := refreshSnapshot(config)
+	if err != nil {
+		t.Fatalf("refreshSnapshot() returned an error: %v", err)
+	}
+
+	if !opts.Since.Equal(day(2)) {
+		t.Errorf("Expected to fetch stars since %v, got %v", day(2), opts.Since)
+	}
+	names := make([]string, 0)
+	for _, s := range snap.Stars {
+		names = append(names, s.NameWithOwner)
+	}
+	if want := []string{"user/repo3", "user/new-name", "user/repo1"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected merged stars %v, got %v", want, names)
+	}
+	if !snap.FullFetchAt.Equal(prev.FullFetchAt) {
+		t.Errorf("Expected full fetch time to be kept, got %v", snap.FullFetchAt)
+	}
+
+	// a full refresh is due after FullRefreshDays
+	config.FullRefreshDays = 1
+	if _, _, err := refreshSnapshot(config); err != nil {
+		t.Fatalf("refreshSnapshot() returned an error: %v", err)
+	}
+	if !opts.Since.IsZero() {
+		t.Errorf("Expected a full fetch, got since %v", opts.Since)
+	}
+}
+
+func TestGithubSourceStopsAtSince(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	pages := map[string]string{
+		"": `{"isOverLimit":false,"totalCount":3,"edges":[
+			{"starredAt":"2024-05-03T10:00:00Z","node":{"id":"R3","name":"repo3","nameWithOwner":"user/repo3","url":"https://github.com/user/repo3"}},
+			{"starredAt":"2024-05-01T10:00:00Z","node":{"id":"R1","name":"repo1","nameWithOwner":"user/repo1","url":"https://github.com/user/repo1"}}],
+			"pageInfo":{"endCursor":"c1","hasNextPage":true}}`,
+	}
+	srv := httptest.NewServer(graphqlPages(t, "null", pages))
+	defer srv.Close()
+
+	stars, err := githubSource{}.FetchStars(context.Background(), FetchOptions{
+		User:      "octocat",
+		Token:     "testtoken",
+		Endpoint:  srv.URL + "/api/graphql",
+		RateLimit: 100,
+		Since:     time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("FetchStars() returned an error: %v", err)
+	}
+	if len(stars) != 1 || stars[0].ID != "R3" {
+		t.Errorf("Expected only the star newer than since, got %+v", stars)
+	}
+}
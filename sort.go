@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	SortByName      = "name"
+	SortByStars     = "stars"
+	SortByStarredAt = "starred-at"
+	SortByPushedAt  = "pushed-at"
+
+	GroupSortByName   = "name"
+	GroupSortByCount  = "count"
+	GroupSortByCustom = "custom"
+
+	sortAsc  = "asc"
+	sortDesc = "desc"
+)
+
+var (
+	availableSorts      = []string{SortByName, SortByStars, SortByStarredAt, SortByPushedAt}
+	availableGroupSorts = []string{GroupSortByName, GroupSortByCount, GroupSortByCustom}
+)
+
+// parseSort splits a sort specification like "stars:desc" into field and direction.
+// Without direction, name and custom order sort ascending, everything else descending.
+func parseSort(spec, def string, fields []string) (field string, desc bool, err error) {
+	field, dir, _ := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), ":")
+	if field == "" {
+		field = def
+	}
+
+	known := false
+	for _, f := range fields {
+		if f == field {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return "", false, fmt.Errorf("unknown sort order %q, available: %s", field, strings.Join(fields, ", "))
+	}
+
+	switch dir {
+	case "":
+		desc = field != SortByName && field != GroupSortByCustom
+	case sortAsc:
+		desc = false
+	case sortDesc:
+		desc = true
+	default:
+		return "", false, fmt.Errorf("unknown sort direction %q, use %s or %s", dir, sortAsc, sortDesc)
+	}
+	return field, desc, nil
+}
+
+// sortStars sorts the stars of every group. Ties are broken by name.
+func sortStars(stars map[string][]Star, spec string) error {
+	field, desc, err := parseSort(spec, SortByName, availableSorts)
+	if err != nil {
+		return err
+	}
+
+	name := func(s Star) string { return strings.ToLower(s.NameWithOwner) }
+	cmp := func(a, b Star) int {
+		switch field {
+		case SortByStars:
+			return a.Stars - b.Stars
+		case SortByStarredAt:
+			return a.StarredAt.Compare(b.StarredAt)
+		case SortByPushedAt:
+			return a.PushedAt.Compare(b.PushedAt)
+		default:
+			return strings.Compare(name(a), name(b))
+		}
+	}
+
+	for k, v := range stars {
+		sort.SliceStable(v, func(i, j int) bool {
+			c := cmp(v[i], v[j])
+			if c == 0 {
+				return name(v[i]) < name(v[j])
+			}
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		})
+		stars[k] = v
+	}
+	return nil
+}
+
+// sortKeys returns the group keys in the order given by spec. With custom order,
+// the groups listed in order come first, the remaining follow sorted by name.
+func sortKeys(stars map[string][]Star, spec string, order []string) ([]string, error) {
+	field, desc, err := parseSort(spec, GroupSortByName, availableGroupSorts)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(stars))
+	for k := range stars {
+		keys = append(keys, k)
+	}
+
+	rank := make(map[string]int, len(order))
+	for i, k := range order {
+		if _, ok := rank[strings.ToLower(k)]; !ok {
+			rank[strings.ToLower(k)] = i
+		}
+	}
+	pos := func(k string) int {
+		if r, ok := rank[strings.ToLower(k)]; ok {
+			return r
+		}
+		return len(order)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		var c int
+		switch field {
+		case GroupSortByName:
+			c = strings.Compare(a, b)
+		case GroupSortByCount:
+			c = len(stars[a]) - len(stars[b])
+		case GroupSortByCustom:
+			c = pos(a) - pos(b)
+		}
+		if c == 0 {
+			return a < b
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	return keys, nil
+}
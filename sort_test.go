@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSortStars(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	list := []Star{
+		{NameWithOwner: "b/beta", Stars: 10, StarredAt: day(1), PushedAt: day(3)},
+		{NameWithOwner: "A/alpha", Stars: 5, StarredAt: day(3), PushedAt: day(1)},
+		{NameWithOwner: "c/gamma", Stars: 10, StarredAt: day(2), PushedAt: day(2)},
+	}
+
+	tests := []struct {
+		spec     string
+		expected []string
+	}{
+		{"", []string{"A/alpha", "b/beta", "c/gamma"}},
+		{"name:desc", []string{"c/gamma", "b/beta", "A/alpha"}},
+		{"stars", []string{"b/beta", "c/gamma", "A/alpha"}},
+		{"stars:asc", []string{"A/alpha", "b/beta", "c/gamma"}},
+		{"starred-at", []string{"A/alpha", "c/gamma", "b/beta"}},
+		{"pushed-at:asc", []string{"A/alpha", "c/gamma", "b/beta"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			stars := map[string][]Star{"x": append([]Star(nil), list...)}
+			if err := sortStars(stars, tt.spec); err != nil {
+				t.Fatalf("sortStars() returned an error: %v", err)
+			}
+			names := make([]string, 0)
+			for _, s := range stars["x"] {
+				names = append(names, s.NameWithOwner)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("sortStars() = %v, want %v", names, tt.expected)
+			}
+		})
+	}
+
+	for _, spec := range []string{"forks", "stars:up"} {
+		if err := sortStars(map[string][]Star{}, spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	stars := map[string][]Star{
+		"Go":     make([]Star, 3),
+		"Rust":   make([]Star, 1),
+		"HTML":   make([]Star, 3),
+		"Python": make([]Star, 2),
+	}
+
+	tests := []struct {
+		spec     string
+		order    []string
+		expected []string
+	}{
+		{"", nil, []string{"Go", "HTML", "Python", "Rust"}},
+		{"name:desc", nil, []string{"Rust", "Python", "HTML", "Go"}},
+		{"count", nil, []string{"Go", "HTML", "Python", "Rust"}},
+		{"count:asc", nil, []string{"Rust", "Python", "Go", "HTML"}},
+		{"custom", []string{"rust", "Go"}, []string{"Rust", "Go", "HTML", "Python"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			keys, err := sortKeys(stars, tt.spec, tt.order)
+			if err != nil {
+				t.Fatalf("sortKeys() returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(keys, tt.expected) {
+				t.Errorf("sortKeys() = %v, want %v", keys, tt.expected)
+			}
+		})
+	}
+
+	if _, err := sortKeys(stars, "stars", nil); err == nil {
+		t.Error("Expected an error for an unknown group sort")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	GithubSource = "github"
+	FileSource   = "file"
+	TestSource   = "test"
+)
+
+// FetchOptions holds the parameters a StarSource may use to fetch stars.
+type FetchOptions struct {
+	User      string    // User whose stars are fetched
+	Token     string    // Access token for the API
+	Endpoint  string    // API endpoint, empty for the default
+	RateLimit int       // Number of API requests per second
+	Path      string    // Path to a file containing stars
+	Since     time.Time // If set, stars starred before may be omitted
+}
+
+// StarSource provides the starred repositories of a user.
+type StarSource interface {
+	FetchStars(ctx context.Context, opts FetchOptions) ([]Star, error)
+}
+
+var starSources = map[string]StarSource{
+	GithubSource: githubSource{},
+	FileSource:   fileSource{},
+	TestSource:   testSource{},
+}
+
+// RegisterStarSource makes a StarSource available under the given name.
+// Registering a name twice replaces the previous source.
+func RegisterStarSource(name string, src StarSource) {
+	starSources[strings.ToLower(name)] = src
+}
+
+// lookupStarSource returns the StarSource registered under name.
+func lookupStarSource(name string) (StarSource, error) {
+	if src, ok := starSources[strings.ToLower(name)]; ok {
+		return src, nil
+	}
+	return nil, fmt.Errorf("unknown star source %q, available: %s", name, strings.Join(sourceNames(), ", "))
+}
+
+// sourceNames returns the sorted names of all registered sources.
+func sourceNames() []string {
+	names := make([]string, 0, len(starSources))
+	for n := range starSources {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sourceName returns the name of the star source selected by the configuration.
+func sourceName(config *Config) string {
+	switch {
+	case config.Test:
+		return TestSource
+	case config.Source == "":
+		return GithubSource
+	default:
+		return config.Source
+	}
+}
+
+// fetchStars retrieves the starred repositories from the configured source.
+// A non-zero since allows the source to skip stars starred before.
+func fetchStars(config *Config, since time.Time) ([]Star, error) {
+	src, err := lookupStarSource(sourceName(config))
+	if err != nil {
+		return nil, err
+	}
+
+	stars, err := src.FetchStars(context.Background(), FetchOptions{
+		User:      config.GithubUser,
+		Token:     config.GithubToken,
+		Endpoint:  config.GraphQLURL,
+		RateLimit: config.RateLimit,
+		Path:      config.SourceFile,
+		Since:     since,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stars: %v", err)
+	}
+	return stars, nil
+}
+
+// fileSource reads stars from a JSON file containing a list of Star, e.g. a fixture for tests.
+type fileSource struct{}
+
+func (fileSource) FetchStars(_ context.Context, opts FetchOptions) ([]Star, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("no file given for the %s source", FileSource)
+	}
+	data, err := os.ReadFile(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading stars file: %v", err)
+	}
+	var stars []Star
+	if err := json.Unmarshal(data, &stars); err != nil {
+		return nil, fmt.Errorf("error parsing stars file: %v", err)
+	}
+	return stars, nil
+}
+
+// testSource returns some static test data.
+type testSource struct{}
+
+func (testSource) FetchStars(_ context.Context, _ FetchOptions) ([]Star, error) {
+	return testStars(), nil
+}
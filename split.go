@@ -0,0 +1,129 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultSplitDir = "stars"
+	// splitManifest lists the files written to the split directory, only those are
+	// removed when their group no longer exists.
+	splitManifest = ".stargazer-files"
+)
+
+//go:embed split_index_template.md
+var splitIndexTemplate string
+
+// writeSplit writes a file per group to the split directory, next to the output
+// file, and an index linking to them to the output file, or between its markers
+// in inject mode. Files of groups that no longer exist are removed from the split
+// directory, if the manifest of the previous run lists them.
+func writeSplit(config *Config, r renderer, data T) error {
+	if _, ok := r.(templateRenderer); !ok {
+		return errors.New("split output requires the list or table format or a custom markdown template")
+	}
+
+	base := filepath.Dir(config.OutputFile)
+	dir := config.SplitDir
+	if dir == "" {
+		dir = defaultSplitDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	if same, err := sameDir(dir, base); err != nil {
+		return err
+	} else if same {
+		return fmt.Errorf("split directory %s is the directory of the output file, use a subdirectory", dir)
+	}
+	if err := makeDir(dir); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(config.OutputFile)
+	if ext == "" {
+		ext = ".md"
+	}
+
+	files := make(map[string]string, len(data.Keys))
+	written := make([]string, 0, len(data.Keys))
+	for _, k := range data.Keys {
+		path := filepath.Join(dir, data.Anchors[k]+ext)
+		page := data.page(k, data.Stars[k])
+		page.WithToc = false
+		page.WithBtt = false
+		if err := writeList(path, r, page); err != nil {
+			return fmt.Errorf("error writing %s: %v", path, err)
+		}
+		written = append(written, filepath.Base(path))
+
+		link, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		files[k] = filepath.ToSlash(link)
+	}
+
+	if err := cleanSplitDir(dir, written); err != nil {
+		return err
+	}
+
+	t, err := template.New("index").Funcs(templateFuncs).Parse(splitIndexTemplate)
+	if err != nil {
+		return err
+	}
+	data.Files = files
+	if config.Inject {
+		return injectList(config.OutputFile, templateRenderer{t: t}, data)
+	}
+	return writeList(config.OutputFile, templateRenderer{t: t}, data)
+}
+
+// sameDir reports whether a and b are the same directory.
+func sameDir(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return absA == absB, nil
+}
+
+// cleanSplitDir removes the files of the previous run that weren't written again
+// and records the written files in the manifest. Other files are never touched.
+func cleanSplitDir(dir string, written []string) error {
+	manifest := filepath.Join(dir, splitManifest)
+	var previous []string
+	if b, err := os.ReadFile(manifest); err == nil {
+		previous = strings.Fields(string(b))
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error reading %s: %v", manifest, err)
+	}
+
+	for _, name := range previous {
+		// the manifest only lists plain file names
+		if name != filepath.Base(name) || strings.HasPrefix(name, ".") || slices.Contains(written, name) {
+			continue
+		}
+		if path := filepath.Join(dir, name); exists(path) {
+			if err := removeFile(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	sort.Strings(written)
+	_, err := writeFile(manifest, []byte(strings.Join(written, "\n")+"\n"))
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteSplit(t *testing.T) {
+	dir := t.TempDir()
+	config := &Config{OutputFile: filepath.Join(dir, "README.md"), OutputFormat: "list", SplitDir: "stars"}
+	if err := os.MkdirAll(filepath.Join(dir, "stars"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"stars/gone.md":          "",
+		"stars/notes.md":         "",
+		"keep.md":                "",
+		"stars/" + splitManifest: "gone.md\nmissing.md\n../keep.md\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := newRenderer(config)
+	if err != nil {
+		t.Fatalf("newRenderer() returned an error: %v", err)
+	}
+	data := testT()
+	data.WithBtt = true
+	if err := writeSplit(config, r, data); err != nil {
+		t.Fatalf("writeSplit() returned an error: %v", err)
+	}
+
+	index, err := os.ReadFile(config.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{"  - [Go](stars/go.md) (1)", "  - [Rust](stars/rust.md) (1)", "Total starred repositories: `2`"} {
+		if !strings.Contains(string(index), e) {
+			t.Errorf("Expected %q in index:\n%s", e, index)
+		}
+	}
+
+	tests := []struct {
+		file     string
+		contains string
+		missing  string
+	}{
+		{"go.md", "[user/repo1](https://github.com/user/repo1)", "user/repo2"},
+		{"rust.md", "[user/repo2](https://github.com/user/repo2)", "#contents"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(dir, "stars", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), tt.contains) {
+				t.Errorf("Expected %q in %s:\n%s", tt.contains, tt.file, b)
+			}
+			if strings.Contains(string(b), tt.missing) {
+				t.Errorf("Unexpected %q in %s:\n%s", tt.missing, tt.file, b)
+			}
+		})
+	}
+
+	if exists(filepath.Join(dir, "stars", "gone.md")) {
+		t.Error("Expected the file of the removed group to be deleted")
+	}
+	if !exists(filepath.Join(dir, "stars", "notes.md")) || !exists(filepath.Join(dir, "keep.md")) {
+		t.Error("Expected the files not written by stargazer to be kept")
+	}
+	if b := mustReadFile(t, filepath.Join(dir, "stars", splitManifest)); string(b) != "go.md\nrust.md\n" {
+		t.Errorf("Unexpected manifest %q", b)
+	}
+
+	for _, splitDir := range []string{".", dir} {
+		c := *config
+		c.SplitDir = splitDir
+		if err := writeSplit(&c, r, data); err == nil || !strings.Contains(err.Error(), "use a subdirectory") {
+			t.Errorf("Expected an error for the split directory %s, got %v", splitDir, err)
+		}
+	}
+
+	if err := writeSplit(config, jsonRenderer{}, data); err == nil {
+		t.Error("Expected an error splitting a non-template format")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the sqlite driver
+)
+
+const SQLiteFormat = "sqlite"
+
+// sqliteSchema creates the tables of the sqlite format. Repositories are keyed by
+// their node ID, or URL for older snapshots, see starKey.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	fetched_at TEXT PRIMARY KEY,
+	user       TEXT NOT NULL,
+	group_by   TEXT NOT NULL,
+	total      INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS languages (
+	name TEXT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS repositories (
+	key             TEXT PRIMARY KEY,
+	id              TEXT,
+	url             TEXT NOT NULL,
+	name            TEXT NOT NULL,
+	name_with_owner TEXT NOT NULL,
+	owner           TEXT NOT NULL,
+	description     TEXT NOT NULL,
+	language        TEXT REFERENCES languages (name),
+	license         TEXT NOT NULL,
+	license_url     TEXT NOT NULL,
+	stars           INTEGER NOT NULL,
+	archived        INTEGER NOT NULL,
+	starred         INTEGER NOT NULL,
+	starred_at      TEXT,
+	pushed_at       TEXT,
+	first_seen      TEXT NOT NULL,
+	last_seen       TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS topics (
+	repository TEXT NOT NULL REFERENCES repositories (key) ON DELETE CASCADE,
+	topic      TEXT NOT NULL,
+	PRIMARY KEY (repository, topic)
+);
+CREATE TABLE IF NOT EXISTS repository_history (
+	fetched_at TEXT NOT NULL REFERENCES snapshots (fetched_at) ON DELETE CASCADE,
+	repository TEXT NOT NULL REFERENCES repositories (key) ON DELETE CASCADE,
+	stars      INTEGER NOT NULL,
+	archived   INTEGER NOT NULL,
+	PRIMARY KEY (fetched_at, repository)
+);
+`
+
+// fileRenderer is implemented by renderers that write a file themselves instead of a stream.
+type fileRenderer interface {
+	renderFile(path string, data T) error
+}
+
+// sqliteRenderer writes the listed stars to a SQLite database. An existing
+// database is updated in place: repositories are updated, repositories that are
+// no longer starred are kept with starred = 0, and star counts are recorded per
+// fetch time in repository_history. The starred flag is based on all stars of
+// the snapshot, so repositories that are only filtered out stay starred.
+type sqliteRenderer struct{}
+
+func (sqliteRenderer) render(io.Writer, T) error {
+	return errors.New("the sqlite format can only be written to a file")
+}
+
+func (sqliteRenderer) renderFile(path string, data T) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		return err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("error creating tables: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := writeSQLite(tx, data); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error writing database: %v", err)
+	}
+	return tx.Commit()
+}
+
+func writeSQLite(tx *sql.Tx, data T) error {
+	fetchedAt := data.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now().UTC()
+	}
+	seen := sqlTime(fetchedAt)
+
+	if _, err := tx.Exec(`INSERT INTO snapshots (fetched_at, user, group_by, total) VALUES (?, ?, ?, ?)
+		ON CONFLICT (fetched_at) DO UPDATE SET user = excluded.user, group_by = excluded.group_by, total = excluded.total`,
+		seen, data.User, data.GroupBy, data.Total); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE repositories SET starred = 0`); err != nil {
+		return err
+	}
+	for _, s := range data.All {
+		if _, err := tx.Exec(`UPDATE repositories SET starred = 1 WHERE key = ?`, starKey(s)); err != nil {
+			return err
+		}
+	}
+
+	done := make(map[string]bool)
+	for _, s := range listedStars(data) {
+		key := starKey(s)
+		if done[key] {
+			continue
+		}
+		done[key] = true
+
+		var lang interface{}
+		if s.Language != "" {
+			lang = s.Language
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO languages (name) VALUES (?)`, s.Language); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(`INSERT INTO repositories (key, id, url, name, name_with_owner, owner, description,
+			language, license, license_url, stars, archived, starred, starred_at, pushed_at, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?, ?)
+			ON CONFLICT (key) DO UPDATE SET id = excluded.id, url = excluded.url, name = excluded.name,
+			name_with_owner = excluded.name_with_owner, owner = excluded.owner, description = excluded.description,
+			language = excluded.language, license = excluded.license, license_url = excluded.license_url,
+			stars = excluded.stars, archived = excluded.archived, starred = 1, starred_at = excluded.starred_at,
+			pushed_at = excluded.pushed_at, last_seen = excluded.last_seen`,
+			key, s.ID, s.Url, s.Name, s.NameWithOwner, s.Owner(), s.Description,
+			lang, s.License, s.LicenseUrl, s.Stars, s.Archived, sqlTime(s.StarredAt), sqlTime(s.PushedAt), seen, seen); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM topics WHERE repository = ?`, key); err != nil {
+			return err
+		}
+		for _, t := range s.Topics {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO topics (repository, topic) VALUES (?, ?)`, key, strings.ToLower(t)); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(`INSERT INTO repository_history (fetched_at, repository, stars, archived) VALUES (?, ?, ?, ?)
+			ON CONFLICT (fetched_at, repository) DO UPDATE SET stars = excluded.stars, archived = excluded.archived`,
+			seen, key, s.Stars, s.Archived); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listedStars returns the stars of all groups, a star can be in several groups.
+func listedStars(data T) []Star {
+	var list []Star
+	for _, k := range data.Keys {
+		list = append(list, data.Stars[k]...)
+	}
+	return list
+}
+
+// sqlTime formats t for storage, the zero time is stored as NULL.
+func sqlTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"bytes"
 	_ "embed"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
-	"sort"
+	"slices"
 	"strings"
 	"text/template"
+	"time"
 )
 
 type TemplateType string
@@ -21,7 +25,7 @@ const (
 	creditUrl  = "https://github.com/jmelfi/stargazer"
 )
 
-var availableFormats = []string{string(ListTemplate), string(TableTemplate)}
+var availableFormats = []string{string(ListTemplate), string(TableTemplate), JSONFormat, YAMLFormat, CSVFormat, TSVFormat, BookmarksFormat, AtomFormat, RSSFormat, OPMLFormat, SQLiteFormat, HTMLFormat}
 
 //go:embed list_template.md
 var list string
@@ -29,9 +33,11 @@ var list string
 //go:embed table_template.md
 var table string
 
-var temp *template.Template
-
 type T struct {
+	User        string    // User whose stars are listed
+	Title       string    // Title of the page, set for the pages of the site
+	FetchedAt   time.Time // When the stars were fetched
+	GroupBy     string    // How the stars are grouped
 	Total       int
 	WithToc     bool
 	WithLicense bool
@@ -40,95 +46,276 @@ type T struct {
 	Keys        []string
 	Anchors     map[string]string
 	Stars       map[string][]Star
+	All         []Star            // All stars of the snapshot, before filtering and grouping
+	Files       map[string]string // Files of the groups relative to the index, set in split mode
+	Changes     *Changelog        // Changes since the previous run, nil if unknown
 	Credits     C
 }
 
+// Group is the model of the group partial of the templates.
+type Group struct {
+	Key    string
+	Anchor string
+	Stars  []Star
+	List   T // The whole list, for its settings
+}
+
+// Entry is the model of the entry partial of the templates.
+type Entry struct {
+	Star
+	List T // The whole list, for its settings
+}
+
+// group returns the model of the group partial for the key.
+func group(data T, key string) Group {
+	return Group{Key: key, Anchor: data.Anchors[key], Stars: data.Stars[key], List: data}
+}
+
+// entry returns the model of the entry partial for the star.
+func entry(data T, s Star) Entry {
+	return Entry{Star: s, List: data}
+}
+
 type C struct {
 	Text string
 	Url  string
 	Link string
 }
 
-func initTemplate(tType string) (err error) {
-	var t string
-	switch tType {
-	case "table":
-		t = table
-	case "list":
-		t = list
-	default:
-		if exists(tType) {
-			if b, err := os.ReadFile(tType); err != nil {
-				fmt.Printf("cannot read custom template: %s\n%v\n", tType, err)
-			} else {
-				t = string(b)
-			}
-		} else {
-			t = list
+// templatePartials are the partials of the list and table templates, which a
+// template directory can replace besides the main template.
+var templatePartials = []string{"header", "group", "entry", "footer"}
+
+// initTemplate parses the template of the format, the list or table template or
+// the path of a custom one, with the main template and partials of the directory.
+func initTemplate(tType, dir string) (*template.Template, error) {
+	base := list
+	if tType == string(TableTemplate) {
+		base = table
+	}
+	t, err := template.New("readme").Funcs(templateFuncs).Parse(base)
+	if err != nil {
+		return nil, err
+	}
+
+	if tType != string(ListTemplate) && tType != string(TableTemplate) {
+		custom, err := readTemplate(tType)
+		if err != nil {
+			return nil, err
+		}
+		// custom templates can use and replace the partials of the list template
+		if _, err := t.Parse(custom); err != nil {
+			return nil, fmt.Errorf("cannot parse template %s: %v", tType, err)
+		}
+	}
+
+	if dir != "" {
+		if err := parseTemplateDir(t, dir); err != nil {
+			return nil, err
 		}
 	}
+	return t, nil
+}
 
-	temp, err = template.New("readme").Parse(t)
+// readTemplate reads a custom template.
+func readTemplate(path string) (string, error) {
+	fi, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return "", fmt.Errorf("unknown format or template %q, use one of %s or the path of a template", path, strings.Join(availableFormats, ", "))
+	case err != nil:
+		return "", fmt.Errorf("cannot read template %s: %v", path, err)
+	case fi.IsDir():
+		return "", fmt.Errorf("template %s is a directory, use --list-template-dir for a directory of templates", path)
+	}
 
-	return
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read template %s: %v", path, err)
+	}
+	return string(b), nil
 }
 
-func writeList(path string, stars map[string][]Star, total int, withToc, withLicense, withStars, withBtt bool) error {
-	if temp == nil {
-		return errors.New("template not initialized")
+// parseTemplateDir parses the main template and the partials in the directory
+// into t, replacing those of t. The files are named after the template they
+// replace, with any extension, e.g. main.md or entry.tmpl.
+func parseTemplateDir(t *template.Template, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("cannot read template directory: %v", err)
 	}
 
-	if exists(path) {
-		err := os.Remove(path)
+	parsed := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		if name != "main" && !slices.Contains(templatePartials, name) {
+			return fmt.Errorf("unknown template %s, expected main or one of the partials %s", path, strings.Join(templatePartials, ", "))
+		}
+		if other, ok := parsed[name]; ok {
+			return fmt.Errorf("templates %s and %s both replace %s", other, path, name)
+		}
+		parsed[name] = path
+
+		b, err := os.ReadFile(path)
 		if err != nil {
-			return err
+			return fmt.Errorf("cannot read template %s: %v", path, err)
+		}
+		tmpl := t
+		if name != "main" {
+			tmpl = t.New(name)
 		}
+		if _, err := tmpl.Parse(string(b)); err != nil {
+			return fmt.Errorf("cannot parse template %s: %v", path, err)
+		}
+	}
+
+	if len(parsed) == 0 {
+		return fmt.Errorf("no templates found in %s", dir)
 	}
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o665)
+	return nil
+}
+
+// templateRenderer renders the model with a text template.
+type templateRenderer struct {
+	t      *template.Template
+	escape escapeContext // how the template uses the descriptions
+}
+
+func (r templateRenderer) render(w io.Writer, data T) error {
+	return r.t.Execute(w, data)
+}
+
+// newT builds the template model for the grouped stars of a snapshot.
+func newT(config *Config, snap *Snapshot, stars map[string][]Star, total int, changes *Changelog) (T, error) {
+	keys, err := sortKeys(stars, config.GroupSort, config.GroupOrder)
 	if err != nil {
+		return T{}, err
+	}
+
+	return T{
+		User:      snap.User,
+		FetchedAt: snap.FetchedAt,
+		GroupBy:   grouping(config),
+		Keys:      keys,
+		Anchors:   toc(keys),
+		Stars:     stars,
+		All:       snap.Stars,
+		Total:     total,
+		Changes:   changes,
+		Credits: C{
+			Text: creditText,
+			Url:  creditUrl,
+			Link: "[stargazer](" + creditUrl + ")!",
+		},
+		WithToc:     config.WithTOC,
+		WithLicense: config.WithLicense,
+		WithStars:   config.WithStars,
+		WithBtt:     config.WithBackToTop,
+	}, nil
+}
+
+// writeList renders the list to a buffer and then replaces the file atomically, so
+// a failing template never leaves a partial file behind. The file is not touched
+// if its content doesn't change.
+func writeList(path string, r renderer, data T) error {
+	if r == nil {
+		return errors.New("renderer not initialized")
+	}
+	if fr, ok := r.(fileRenderer); ok {
+		if dryRun {
+			logger.WithField("file", path).Warn("Output format can't be compared, skipped in dry-run and check mode")
+			return nil
+		}
+		return fr.renderFile(path, data)
+	}
+
+	var buf bytes.Buffer
+	if err := r.render(&buf, data); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	err = f.Truncate(0)
+	changed, err := writeFile(path, buf.Bytes())
 	if err != nil {
 		return err
 	}
+	if !changed {
+		logger.WithField("file", path).Info("Output unchanged, skipped writing")
+	}
+	return nil
+}
+
+// writeFile writes content to a temporary file next to path and renames it into
+// place. It reports false and doesn't write anything if the file already has the
+// content. The permissions of an existing file are kept. In dry-run mode the change
+// is only recorded.
+func writeFile(path string, content []byte) (bool, error) {
+	mode := os.FileMode(0o644)
+	var old []byte
+	fi, err := os.Stat(path)
+	if err == nil {
+		if fi.IsDir() {
+			return false, fmt.Errorf("%s is a directory", path)
+		}
+		mode = fi.Mode().Perm()
+
+		if old, err = os.ReadFile(path); err != nil {
+			return false, err
+		}
+		if bytes.Equal(old, content) {
+			return false, nil
+		}
+	}
+
+	if dryRun {
+		pendingChanges = append(pendingChanges, fileChange{path: path, old: old, new: content, created: fi == nil})
+		return true, nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op after the rename
 
-	c := C{
-		Text: creditText,
-		Url:  creditUrl,
-		Link: "[stargazer](" + creditUrl + ")!",
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, err
 	}
 
-	keys := make([]string, 0)
-	for k := range stars {
-		keys = append(keys, k)
+	if err := os.Rename(tmp, path); err != nil {
+		return false, err
 	}
-	sort.Strings(keys)
+	return true, nil
+}
+
+var punctuation = regexp.MustCompile(`[^\w\- ]`) // regexp to remove all punctuation
 
-	return temp.Execute(f, T{
-		Keys:        keys,
-		Anchors:     toc(keys),
-		Stars:       stars,
-		Total:       total,
-		Credits:     c,
-		WithToc:     withToc,
-		WithLicense: withLicense,
-		WithStars:   withStars,
-		WithBtt:     withBtt,
-	})
+// anchor returns the heading anchor of a group name, without the suffix toc
+// appends to duplicates.
+func anchor(key string) string {
+	x := strings.ToLower(strings.TrimSpace(key))
+	x = punctuation.ReplaceAllString(x, "")
+	return strings.ReplaceAll(x, " ", "-")
 }
 
 // toc returns the anchors for the table of contents
 func toc(keys []string) map[string]string {
-	rx := regexp.MustCompile(`[^\w\- ]`) // regexp to remove all punctuation
 	anchors := make(map[string]string, 0)
 
 	for _, k := range keys {
-		x := strings.ToLower(strings.TrimSpace(k))
-		x = rx.ReplaceAllString(x, "")
-		x = strings.ReplaceAll(x, " ", "-")
+		x := anchor(k)
 
 		c := 0
 		for {